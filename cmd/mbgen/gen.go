@@ -0,0 +1,483 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"ckklearn.com/testmodbus/global"
+	"ckklearn.com/testmodbus/mbrtu"
+	"ckklearn.com/testmodbus/mbrtu/mbcodec"
+	"ckklearn.com/testmodbus/mbrtu/profile"
+)
+
+// 驱动的模板数据
+type driver struct {
+	Source   string // 描述文件名
+	Package  string
+	Type     string
+	Title    string // 设备类型名称
+	Slave    byte
+	CrcOrder string // crc字节序的表达式
+	Dialect  string
+	Points   []*point
+	Blocks   []*block
+	RegBlock bool // 是否有寄存器块
+	BitBlock bool // 是否有线圈或离散输入块
+	Fmt      bool // 生成的代码是否使用fmt
+}
+
+// 测点的模板数据
+type point struct {
+	Name     string   // 读取函数名
+	Setter   string   // 写入函数名，不可写时为空
+	Var      string   // 转换参数的变量名，不需要转换时为空
+	Conv     string   // 转换参数的表达式
+	Decode   string   // 解码函数名，线圈和离散输入为空
+	GoType   string   // 读取的go类型
+	SetType  string   // 写入的go类型
+	Zero     string   // go类型的零值
+	Doc      []string // 读取函数的注释
+	Area     string   // 数据区的表达式
+	Offset   uint16
+	Num      uint16
+	Readable bool
+	Bit      bool
+	Body     string // 解码函数体
+	Write    string // 写入函数体
+
+	area global.Area
+}
+
+// 合并读取的块
+type block struct {
+	area   global.Area
+	Area   string
+	Desc   string
+	Bit    bool
+	Offset uint16
+	Num    uint16
+	Items  []blockItem
+}
+
+// 块中的测点
+type blockItem struct {
+	Point *point
+	Start int // 线圈和离散输入为位序号，寄存器为字节序号
+	End   int
+}
+
+var areaNames = map[global.Area]string{
+	global.AreaCoil:            "线圈",
+	global.AreaDiscreteInput:   "离散输入",
+	global.AreaInputRegister:   "输入寄存器",
+	global.AreaHoldingRegister: "保持寄存器",
+}
+
+var areaExprs = map[global.Area]string{
+	global.AreaCoil:            "global.AreaCoil",
+	global.AreaDiscreteInput:   "global.AreaDiscreteInput",
+	global.AreaInputRegister:   "global.AreaInputRegister",
+	global.AreaHoldingRegister: "global.AreaHoldingRegister",
+}
+
+// 数值类型对应的go类型和编解码函数的后缀
+var typeNames = map[mbcodec.Type][2]string{
+	mbcodec.Int16:   {"int16", "Int16s"},
+	mbcodec.Uint16:  {"uint16", "Uint16s"},
+	mbcodec.Int32:   {"int32", "Int32s"},
+	mbcodec.Uint32:  {"uint32", "Uint32s"},
+	mbcodec.Int64:   {"int64", "Int64s"},
+	mbcodec.Uint64:  {"uint64", "Uint64s"},
+	mbcodec.Float32: {"float32", "Float32s"},
+	mbcodec.Float64: {"float64", "Float64s"},
+}
+
+// 生成驱动代码
+// `opt` 为ReadAll合并读取的选项
+func generate(p *profile.Profile, source, pkg, typ string, opt mbrtu.CoalesceOptions) ([]byte, error) {
+	d := &driver{
+		Source:  source,
+		Package: pkg,
+		Type:    typ,
+		Title:   p.Name,
+		Slave:   p.Slave,
+		Dialect: p.Dialect,
+	}
+	switch p.CrcOrderValue() {
+	case binary.BigEndian:
+		d.CrcOrder = "binary.BigEndian"
+	case mbrtu.AutoCrcOrder:
+		d.CrcOrder = "mbrtu.AutoCrcOrder"
+	default:
+		d.CrcOrder = "binary.LittleEndian"
+	}
+
+	// 生成的标识符不能重复
+	used := map[string]bool{
+		typ: true, "New" + typ: true, typ + "Values": true,
+		"ReadAll": true, "readRegs": true, "readBits": true, "writeRegs": true,
+	}
+	claim := func(name string) error {
+		if used[name] {
+			return fmt.Errorf("generated name `%s` conflicts", name)
+		}
+		used[name] = true
+		return nil
+	}
+
+	for i := range p.Points {
+		pt, err := newPoint(&p.Points[i])
+		if err != nil {
+			return nil, fmt.Errorf("point %s: %v", p.Points[i].Name, err)
+		}
+		names := []string{pt.Name}
+		if pt.Setter != "" {
+			names = append(names, pt.Setter)
+		}
+		if pt.Decode != "" {
+			names = append(names, pt.Decode)
+		}
+		if pt.Var != "" {
+			names = append(names, pt.Var)
+		}
+		for _, name := range names {
+			err = claim(name)
+			if err != nil {
+				return nil, fmt.Errorf("point %s: %v", p.Points[i].Name, err)
+			}
+		}
+		d.Points = append(d.Points, pt)
+		if strings.Contains(pt.Body+pt.Write, "fmt.") {
+			d.Fmt = true
+		}
+	}
+	blocks, err := planBlocks(d.Points, opt)
+	if err != nil {
+		return nil, err
+	}
+	d.Blocks = blocks
+	for _, b := range d.Blocks {
+		if b.Bit {
+			d.BitBlock = true
+		} else {
+			d.RegBlock = true
+		}
+	}
+
+	var buf bytes.Buffer
+	err = driverTemplate.Execute(&buf, d)
+	if err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %v", err)
+	}
+	return src, nil
+}
+
+// 构造测点的模板数据
+func newPoint(raw *profile.Point) (*point, error) {
+	area := raw.AreaValue()
+	name := identifier(raw.Name, true)
+	pt := &point{
+		Name:     name,
+		Area:     areaExprs[area],
+		area:     area,
+		Offset:   raw.Offset,
+		Num:      raw.Registers(),
+		Readable: raw.Readable(),
+		Bit:      area.IsBit(),
+	}
+	if raw.Writable() {
+		pt.Setter = "Set" + name
+	}
+	if !pt.Bit && pt.Readable {
+		pt.Decode = "decode" + name
+	}
+	write := fmt.Sprintf("return d.writeRegs(%d, regs)", raw.Offset)
+
+	order := "mbcodec." + raw.OrderValue().String()
+	conv := raw.Converter()
+	opt, isStr := raw.StringOptions()
+	names, typed := typeNames[raw.TypeValue()]
+	// 只配置了无效值的测点不需要工程转换
+	sentinel := len(conv.Invalid) > 0 && conv.IsIdentity() && conv.Enum == nil && conv.Bits == nil
+	switch {
+	case pt.Bit:
+		pt.GoType, pt.Zero = "bool", "false"
+		pt.Write = fmt.Sprintf("return d.m.WriteSingleCoil(d.slave, %d, v, d.crcOrder)", raw.Offset)
+
+	case isStr:
+		opts := fmt.Sprintf("mbcodec.StringOptions{Charset: mbcodec.%s, SwapBytes: %t}", strings.ToUpper(string(opt.Charset)), opt.SwapBytes)
+		pt.GoType, pt.Zero = "string", `""`
+		pt.Body = fmt.Sprintf("return mbcodec.String(b, %s)", opts)
+		pt.Write = fmt.Sprintf("regs, err := mbcodec.EncodeString(v, %d, %s)\nif err != nil {\nreturn err\n}\n%s", raw.Length, opts, write)
+
+	case sentinel && typed:
+		// 保留原始的go类型，读到无效值时返回错误
+		pt.Var = identifier(raw.Name, false) + "Conv"
+		pt.Conv = converterExpr(conv)
+		pt.GoType, pt.Zero = names[0], "0"
+		pt.Body = fmt.Sprintf("v, err := %s.Convert(b, mbcodec.%s, %s)\nif err != nil {\nreturn 0, err\n}\n"+
+			"if !v.Valid {\nreturn 0, fmt.Errorf(%s, v.Bits)\n}\nreturn %s(v.Raw.(%s)), nil",
+			pt.Var, typeConst(raw.TypeValue()), order, strconv.Quote(raw.Name+": invalid value %#x"), names[0], rawType(raw.TypeValue()))
+		pt.Write = fmt.Sprintf("return d.writeRegs(%d, mbcodec.Encode%s([]%s{v}, %s))", raw.Offset, names[1], names[0], order)
+
+	case !conv.IsIdentity() || len(conv.Invalid) > 0 || conv.Enum != nil || conv.Bits != nil:
+		pt.Var = identifier(raw.Name, false) + "Conv"
+		pt.Conv = converterExpr(conv)
+		convert := fmt.Sprintf("%s.Convert(b, mbcodec.%s, %s)", pt.Var, typeConst(raw.TypeValue()), order)
+		if conv.Enum != nil || conv.Bits != nil {
+			pt.GoType, pt.Zero, pt.SetType = "mbcodec.Value", "mbcodec.Value{}", "float64"
+			pt.Body = "return " + convert
+		} else {
+			pt.GoType, pt.Zero = "float64", "0"
+			pt.Body = fmt.Sprintf("v, err := %s\nif err != nil {\nreturn 0, err\n}\n"+
+				"if !v.Valid {\nreturn 0, fmt.Errorf(%s, v.Bits)\n}\nreturn v.Float, nil",
+				convert, strconv.Quote(raw.Name+": invalid value %#x"))
+		}
+		pt.Write = fmt.Sprintf("regs, err := %s.EncodeValue(%s, mbcodec.%s, %s)\nif err != nil {\nreturn err\n}\n%s",
+			pt.Var, "v", typeConst(raw.TypeValue()), order, write)
+
+	case raw.TypeValue() == mbcodec.Bool:
+		pt.GoType, pt.Zero = "bool", "false"
+		pt.Body = fmt.Sprintf("v, err := mbcodec.Uint16s(b, %s)\nif err != nil {\nreturn false, err\n}\nreturn v[0] != 0, nil", order)
+		pt.Write = fmt.Sprintf("regs, err := mbcodec.Encode(v, mbcodec.Bool, %s)\nif err != nil {\nreturn err\n}\n%s", order, write)
+
+	default:
+		if !typed {
+			return nil, fmt.Errorf("unsupported type `%s`", raw.TypeValue())
+		}
+		pt.GoType, pt.Zero = names[0], "0"
+		pt.Body = fmt.Sprintf("v, err := mbcodec.%s(b, %s)\nif err != nil {\nreturn 0, err\n}\nreturn v[0], nil", names[1], order)
+		pt.Write = fmt.Sprintf("return d.writeRegs(%d, mbcodec.Encode%s([]%s{v}, %s))", raw.Offset, names[1], names[0], order)
+	}
+
+	if pt.SetType == "" {
+		pt.SetType = pt.GoType
+	}
+
+	// 写入前检查工程值的范围
+	if pt.Setter != "" && pt.SetType != "bool" && pt.SetType != "string" {
+		var check string
+		if raw.Min != nil {
+			min := formatFloat(*raw.Min)
+			check += fmt.Sprintf("if float64(v) < %s {\nreturn fmt.Errorf(%s, v)\n}\n",
+				min, strconv.Quote(raw.Name+": value %v below minimum "+min))
+		}
+		if raw.Max != nil {
+			max := formatFloat(*raw.Max)
+			check += fmt.Sprintf("if float64(v) > %s {\nreturn fmt.Errorf(%s, v)\n}\n",
+				max, strconv.Quote(raw.Name+": value %v above maximum "+max))
+		}
+		pt.Write = check + pt.Write
+	}
+
+	pt.Doc = pointDoc(raw)
+	return pt, nil
+}
+
+// 测点的说明，包括单位、范围、地址和编码
+func pointDoc(raw *profile.Point) []string {
+	title := raw.Description
+	if title == "" {
+		title = raw.Name
+	}
+	doc := []string{title}
+
+	var attrs []string
+	if raw.Unit != "" {
+		attrs = append(attrs, "单位："+raw.Unit)
+	}
+	switch {
+	case raw.Min != nil && raw.Max != nil:
+		attrs = append(attrs, fmt.Sprintf("范围：%s~%s", formatFloat(*raw.Min), formatFloat(*raw.Max)))
+	case raw.Min != nil:
+		attrs = append(attrs, "下限："+formatFloat(*raw.Min))
+	case raw.Max != nil:
+		attrs = append(attrs, "上限："+formatFloat(*raw.Max))
+	}
+	if len(attrs) > 0 {
+		doc = append(doc, strings.Join(attrs, "，"))
+	}
+
+	area := raw.AreaValue()
+	addr := fmt.Sprintf("%s%d", areaNames[area], raw.Offset)
+	if n := raw.Registers(); n > 1 {
+		addr += fmt.Sprintf("~%d", raw.Offset+n-1)
+	}
+	attrs = []string{addr}
+	if opt, ok := raw.StringOptions(); ok {
+		attrs = append(attrs, fmt.Sprintf("字符串（%s）", opt.Charset))
+	} else if !area.IsBit() {
+		attrs = append(attrs, string(raw.TypeValue()))
+		if raw.Registers() > 1 {
+			attrs = append(attrs, raw.OrderValue().String())
+		}
+	}
+	conv := raw.Converter()
+	if conv.BCD {
+		attrs = append(attrs, "BCD")
+	}
+	if conv.Gain != 0 && conv.Gain != 1 {
+		attrs = append(attrs, "增益"+formatFloat(conv.Gain))
+	}
+	if conv.Offset != 0 {
+		attrs = append(attrs, "偏移"+formatFloat(conv.Offset))
+	}
+	doc = append(doc, strings.Join(attrs, "，"))
+
+	if conv.Enum != nil {
+		keys := make([]int64, 0, len(conv.Enum))
+		for k := range conv.Enum {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+		items := make([]string, len(keys))
+		for i, k := range keys {
+			items[i] = fmt.Sprintf("%d=%s", k, conv.Enum[k])
+		}
+		doc = append(doc, "枚举："+strings.Join(items, "，"))
+	}
+	return doc
+}
+
+// 转换参数的表达式
+func converterExpr(c *mbcodec.Converter) string {
+	var fields []string
+	if c.BCD {
+		fields = append(fields, "BCD: true")
+	}
+	if c.Gain != 0 {
+		fields = append(fields, "Gain: "+formatFloat(c.Gain))
+	}
+	if c.Offset != 0 {
+		fields = append(fields, "Offset: "+formatFloat(c.Offset))
+	}
+	if len(c.Invalid) > 0 {
+		items := make([]string, len(c.Invalid))
+		for i, v := range c.Invalid {
+			items[i] = fmt.Sprintf("%#x", v)
+		}
+		fields = append(fields, "Invalid: []uint64{"+strings.Join(items, ", ")+"}")
+	}
+	if c.Enum != nil {
+		keys := make([]int64, 0, len(c.Enum))
+		for k := range c.Enum {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+		items := make([]string, len(keys))
+		for i, k := range keys {
+			items[i] = fmt.Sprintf("%d: %q", k, c.Enum[k])
+		}
+		fields = append(fields, "Enum: map[int64]string{"+strings.Join(items, ", ")+"}")
+	}
+	if c.Bits != nil {
+		keys := make([]string, 0, len(c.Bits))
+		for k := range c.Bits {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		items := make([]string, len(keys))
+		for i, k := range keys {
+			items[i] = fmt.Sprintf("%q: %d", k, c.Bits[k])
+		}
+		fields = append(fields, "Bits: map[string]uint{"+strings.Join(items, ", ")+"}")
+	}
+	return "&mbcodec.Converter{" + strings.Join(fields, ", ") + "}"
+}
+
+// 将可读的测点合并为读取的块
+// 按`mbrtu.Coalesce`合并，块的长度不超过单次读取的最大个数
+func planBlocks(points []*point, opt mbrtu.CoalesceOptions) ([]*block, error) {
+	var readable []*point
+	var spans []mbrtu.Span
+	for _, pt := range points {
+		if pt.Readable {
+			readable = append(readable, pt)
+			spans = append(spans, mbrtu.Span{Fun: pt.area.ReadFunCode(), Offset: pt.Offset, Num: pt.Num})
+		}
+	}
+	planned, err := mbrtu.Coalesce(spans, opt)
+	if err != nil {
+		return nil, fmt.Errorf("plan ReadAll: %v", err)
+	}
+
+	blocks := make([]*block, len(planned))
+	for i, rb := range planned {
+		first := readable[rb.Wants[0]]
+		b := &block{area: first.area, Area: first.Area, Bit: first.Bit, Offset: rb.Offset, Num: rb.Num}
+		b.Desc = fmt.Sprintf("%s%d", areaNames[b.area], b.Offset)
+		if b.Num > 1 {
+			b.Desc += fmt.Sprintf("~%d", int(b.Offset)+int(b.Num)-1)
+		}
+		for _, j := range rb.Wants {
+			pt := readable[j]
+			start := int(pt.Offset) - int(b.Offset)
+			item := blockItem{Point: pt, Start: start, End: start + int(pt.Num)}
+			if !pt.Bit {
+				item.Start, item.End = item.Start*2, item.End*2
+			}
+			b.Items = append(b.Items, item)
+		}
+		blocks[i] = b
+	}
+	return blocks, nil
+}
+
+// `mbcodec.Decode` 返回的数值类型
+func rawType(t mbcodec.Type) string {
+	switch t {
+	case mbcodec.Int16, mbcodec.Int32, mbcodec.Int64:
+		return "int64"
+	case mbcodec.Float32, mbcodec.Float64:
+		return "float64"
+	default:
+		return "uint64"
+	}
+}
+
+// 数值类型对应的常量名
+func typeConst(t mbcodec.Type) string {
+	s := string(t)
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// 将名称转换为go标识符
+// 按非字母数字的字符分词，`exported` 为true时首字母大写
+func identifier(s string, exported bool) string {
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	for i, w := range words {
+		rs := []rune(w)
+		if i > 0 || exported {
+			rs[0] = unicode.ToUpper(rs[0])
+		} else {
+			rs[0] = unicode.ToLower(rs[0])
+		}
+		b.WriteString(string(rs))
+	}
+	id := b.String()
+	if id == "" || unicode.IsDigit([]rune(id)[0]) {
+		if exported {
+			id = "P" + id
+		} else {
+			id = "p" + id
+		}
+	}
+	return id
+}
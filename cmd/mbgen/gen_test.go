@@ -0,0 +1,165 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"ckklearn.com/testmodbus/mbrtu"
+	"ckklearn.com/testmodbus/mbrtu/profile"
+)
+
+func parseProfile(t *testing.T, src string) *profile.Profile {
+	t.Helper()
+	p, err := profile.Parse([]byte(src), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestNewPointType(t *testing.T) {
+	tests := []struct {
+		point   string
+		goType  string
+		setType string
+		conv    bool
+	}{
+		{"{name: run, area: coil, offset: 0}", "bool", "bool", false},
+		{"{name: alarm, area: di, offset: 0}", "bool", "", false},
+		{"{name: mode, area: hr, offset: 0}", "uint16", "uint16", false},
+		{"{name: flag, area: hr, offset: 0, type: bool}", "bool", "bool", false},
+		{"{name: temp, area: ir, offset: 0, type: int16}", "int16", "", false},
+		{"{name: energy, area: ir, offset: 0, type: uint64}", "uint64", "", false},
+		{"{name: power, area: ir, offset: 0, type: float32}", "float32", "", false},
+		{"{name: model, area: hr, offset: 0, type: string, length: 8}", "string", "string", false},
+		{"{name: volt, area: ir, offset: 0, scale: 0.1}", "float64", "", true},
+		{"{name: setpoint, area: hr, offset: 0, type: int16, bias: -40}", "float64", "float64", true},
+		{"{name: code, area: ir, offset: 0, bcd: true}", "float64", "", true},
+		{"{name: state, area: hr, offset: 0, enum: {0: off, 1: on}}", "mbcodec.Value", "float64", true},
+		{"{name: status, area: ir, offset: 0, bits: {fault: 0}}", "mbcodec.Value", "", true},
+		// 只配置无效值时保留原始类型
+		{"{name: level, area: ir, offset: 0, type: int16, invalid: [0x7fff]}", "int16", "", true},
+		{"{name: count, area: hr, offset: 0, type: uint32, invalid: [0xffffffff]}", "uint32", "uint32", true},
+		{"{name: flow, area: ir, offset: 0, type: float32, invalid: [0x7fc00000]}", "float32", "", true},
+		{"{name: raw, area: ir, offset: 0, invalid: [0xffff], scale: 2}", "float64", "", true},
+	}
+	for _, tt := range tests {
+		p := parseProfile(t, "points:\n- "+tt.point)
+		pt, err := newPoint(&p.Points[0])
+		if err != nil {
+			t.Errorf("%s: error: %v", tt.point, err)
+			continue
+		}
+		setType := pt.SetType
+		if pt.Setter == "" {
+			setType = ""
+		}
+		if pt.GoType != tt.goType || setType != tt.setType || (pt.Var != "") != tt.conv {
+			t.Errorf("%s: got type %s, set type %s, converter %t, want %s, %s, %t",
+				tt.point, pt.GoType, setType, pt.Var != "", tt.goType, tt.setType, tt.conv)
+		}
+	}
+}
+
+func TestGenerateSentinel(t *testing.T) {
+	p := parseProfile(t, `
+name: meter
+points:
+- {name: level, area: ir, offset: 0, type: int16, invalid: [0x7fff]}
+`)
+	src, err := generate(p, "meter.yaml", "meter", "Meter", mbrtu.CoalesceOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"func (d *Meter) Level() (int16, error)",
+		"return int16(v.Raw.(int64)), nil",
+		`fmt.Errorf("level: invalid value %#x", v.Bits)`,
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated code missing %q", want)
+		}
+	}
+}
+
+func TestPlanBlocks(t *testing.T) {
+	p := parseProfile(t, `
+points:
+- {name: a, area: hr, offset: 0}
+- {name: b, area: hr, offset: 1, type: float32}
+- {name: c, area: hr, offset: 10}
+- {name: d, area: hr, offset: 124, type: uint32}
+- {name: cmd, area: hr, offset: 3, access: w}
+- {name: run, area: coil, offset: 5}
+- {name: trip, area: coil, offset: 7}
+- {name: volt, address: "30001"}
+`)
+	var points []*point
+	for i := range p.Points {
+		pt, err := newPoint(&p.Points[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		points = append(points, pt)
+	}
+
+	// 块描述为"数据区偏移量"加上每个测点在块中的起止位置
+	type item struct {
+		name       string
+		start, end int
+	}
+	tests := []struct {
+		name string
+		opt  mbrtu.CoalesceOptions
+		want map[string][]item
+	}{
+		{"no gap", mbrtu.CoalesceOptions{}, map[string][]item{
+			"保持寄存器0~2":     {{"A", 0, 2}, {"B", 2, 6}},
+			"保持寄存器10":      {{"C", 0, 2}},
+			"保持寄存器124~125": {{"D", 0, 4}},
+			"线圈5":          {{"Run", 0, 1}},
+			"线圈7":          {{"Trip", 0, 1}},
+			"输入寄存器0":       {{"Volt", 0, 2}},
+		}},
+		{"gap", mbrtu.CoalesceOptions{MaxGap: 10}, map[string][]item{
+			"保持寄存器0~10":    {{"A", 0, 2}, {"B", 2, 6}, {"C", 20, 22}},
+			"保持寄存器124~125": {{"D", 0, 4}},
+			"线圈5~7":        {{"Run", 0, 1}, {"Trip", 2, 3}},
+			"输入寄存器0":       {{"Volt", 0, 2}},
+		}},
+		{"read limit", mbrtu.CoalesceOptions{MaxGap: 200}, map[string][]item{
+			"保持寄存器0~10":    {{"A", 0, 2}, {"B", 2, 6}, {"C", 20, 22}},
+			"保持寄存器124~125": {{"D", 0, 4}},
+			"线圈5~7":        {{"Run", 0, 1}, {"Trip", 2, 3}},
+			"输入寄存器0":       {{"Volt", 0, 2}},
+		}},
+		{"forbidden", mbrtu.CoalesceOptions{MaxGap: 10, Forbidden: []mbrtu.Span{{Fun: 0x03, Offset: 5, Num: 1}}}, map[string][]item{
+			"保持寄存器0~2":     {{"A", 0, 2}, {"B", 2, 6}},
+			"保持寄存器10":      {{"C", 0, 2}},
+			"保持寄存器124~125": {{"D", 0, 4}},
+			"线圈5~7":        {{"Run", 0, 1}, {"Trip", 2, 3}},
+			"输入寄存器0":       {{"Volt", 0, 2}},
+		}},
+	}
+	for _, tt := range tests {
+		blocks, err := planBlocks(points, tt.opt)
+		if err != nil {
+			t.Errorf("%s: error: %v", tt.name, err)
+			continue
+		}
+		got := make(map[string][]item, len(blocks))
+		for _, b := range blocks {
+			for _, it := range b.Items {
+				got[b.Desc] = append(got[b.Desc], item{it.Point.Name, it.Start, it.End})
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: blocks = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := planBlocks(points, mbrtu.CoalesceOptions{Forbidden: []mbrtu.Span{{Fun: 0x03, Offset: 1, Num: 1}}}); err == nil {
+		t.Error("point on a forbidden address, want error")
+	}
+}
@@ -0,0 +1,84 @@
+// mbgen 根据设备类型描述文件生成设备驱动
+// 每个测点生成一个读取函数，可写的测点生成写入函数，ReadAll 按`mbrtu.Coalesce`将地址相近的测点合并读取
+//
+// 在驱动所在的包中使用：
+//
+//	//go:generate go run ckklearn.com/testmodbus/cmd/mbgen -in meter.yaml
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"ckklearn.com/testmodbus/global"
+	"ckklearn.com/testmodbus/mbrtu"
+	"ckklearn.com/testmodbus/mbrtu/profile"
+)
+
+func main() {
+	in := flag.String("in", "", "设备类型描述文件（json、yaml或csv）")
+	out := flag.String("out", "", "输出文件，默认为描述文件名加_gen.go")
+	pkg := flag.String("pkg", "", "包名，默认为go generate所在的包或输出目录名")
+	typ := flag.String("type", "", "驱动类型名，默认按设备类型名称生成")
+	gap := flag.Uint("gap", 0, "ReadAll合并读取时一并读取的未使用地址的最大个数")
+	forbid := flag.String("forbid", "", "ReadAll不能读取的地址，以逗号分隔，如40010,40020")
+	flag.Parse()
+
+	err := run(*in, *out, *pkg, *typ, *gap, *forbid)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "mbgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(in, out, pkg, typ string, gap uint, forbid string) error {
+	if in == "" {
+		return fmt.Errorf("-in is required")
+	}
+	if gap > 0xffff {
+		return fmt.Errorf("-gap %d out of range", gap)
+	}
+	opt := mbrtu.CoalesceOptions{MaxGap: uint16(gap)}
+	for _, s := range strings.Split(forbid, ",") {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		a, err := global.ParseAddress(s)
+		if err != nil {
+			return fmt.Errorf("-forbid: %v", err)
+		}
+		opt.Forbidden = append(opt.Forbidden, mbrtu.Span{Fun: a.FunCode(), Offset: a.Offset, Num: 1})
+	}
+	p, err := profile.Load(in)
+	if err != nil {
+		return err
+	}
+
+	if out == "" {
+		base := filepath.Base(in)
+		out = strings.TrimSuffix(base, filepath.Ext(base)) + "_gen.go"
+	}
+	if pkg == "" {
+		pkg = os.Getenv("GOPACKAGE")
+	}
+	if pkg == "" {
+		abs, err := filepath.Abs(filepath.Dir(out))
+		if err != nil {
+			return err
+		}
+		pkg = strings.ToLower(identifier(filepath.Base(abs), false))
+	}
+	if typ == "" {
+		typ = identifier(p.Name, true)
+	}
+
+	src, err := generate(p, filepath.Base(in), pkg, typ, opt)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(out, src, 0644)
+}
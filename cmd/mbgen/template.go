@@ -0,0 +1,166 @@
+package main
+
+import (
+	"strings"
+	"text/template"
+)
+
+var driverTemplate = template.Must(template.New("driver").Funcs(template.FuncMap{
+	"rest": func(s []string) []string { return s[1:] },
+	"lines": func(s string) []string {
+		return strings.Split(s, "\n")
+	},
+}).Parse(`// Code generated by mbgen from {{.Source}}. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"encoding/binary"
+{{- if .Fmt}}
+	"fmt"
+{{- end}}
+
+	"ckklearn.com/testmodbus/global"
+	"ckklearn.com/testmodbus/mbrtu"
+	"ckklearn.com/testmodbus/mbrtu/mbcodec"
+)
+
+// {{.Type}} {{.Title}}设备驱动
+type {{.Type}} struct {
+	m        *mbrtu.RtuMaster
+	slave    byte
+	crcOrder binary.ByteOrder
+}
+
+// New{{.Type}} 构造函数
+{{- if .Slave}}
+// ` + "`slave`" + ` 为0时使用默认从站号{{.Slave}}
+{{- end}}
+// ` + "`crcOrder`" + ` 为nil时使用设备类型的crc字节序
+func New{{.Type}}(m *mbrtu.RtuMaster, slave byte, crcOrder binary.ByteOrder) *{{.Type}} {
+{{- if .Slave}}
+	if slave == 0 {
+		slave = {{.Slave}}
+	}
+{{- end}}
+	if crcOrder == nil {
+		crcOrder = {{.CrcOrder}}
+	}
+{{- if .Dialect}}
+	if dl, ok := mbrtu.LookupDialect({{printf "%q" .Dialect}}); ok {
+		m.SetSlaveDialect(slave, dl)
+	}
+{{- end}}
+	return &{{.Type}}{m: m, slave: slave, crcOrder: crcOrder}
+}
+
+// 读取寄存器，返回寄存器数据
+func (d *{{.Type}}) readRegs(area global.Area, offset, num uint16) ([]byte, error) {
+	return d.m.ReadAreaBytes(d.slave, area, offset, num, d.crcOrder)
+}
+
+// 读取线圈或离散输入，返回布尔值
+func (d *{{.Type}}) readBits(area global.Area, offset, num uint16) ([]bool, error) {
+	p, err := d.m.ReadAreaBytes(d.slave, area, offset, num, d.crcOrder)
+	if err != nil {
+		return nil, err
+	}
+	return mbcodec.Bools(p, int(num))
+}
+
+// 写保持寄存器
+// 单个寄存器使用写单个寄存器功能码
+func (d *{{.Type}}) writeRegs(offset uint16, regs []uint16) error {
+	if len(regs) == 1 {
+		return d.m.WriteSingleRegister(d.slave, offset, regs[0], d.crcOrder)
+	}
+	return d.m.WriteMultiRegisters(d.slave, offset, regs, d.crcOrder)
+}
+{{range .Points}}{{if .Var}}
+// {{.Var}} {{index .Doc 0}}的工程转换
+var {{.Var}} = {{.Conv}}
+{{end}}{{if .Decode}}
+// 解码{{index .Doc 0}}
+func {{.Decode}}(b []byte) ({{.GoType}}, error) {
+{{- range lines .Body}}
+	{{.}}
+{{- end}}
+}
+{{end}}{{if .Readable}}
+// {{.Name}} 读取{{index .Doc 0}}
+{{- range rest .Doc}}
+// {{.}}
+{{- end}}
+func (d *{{$.Type}}) {{.Name}}() ({{.GoType}}, error) {
+{{- if .Bit}}
+	bits, err := d.readBits({{.Area}}, {{.Offset}}, 1)
+	if err != nil {
+		return false, err
+	}
+	return bits[0], nil
+{{- else}}
+	b, err := d.readRegs({{.Area}}, {{.Offset}}, {{.Num}})
+	if err != nil {
+		return {{.Zero}}, err
+	}
+	return {{.Decode}}(b)
+{{- end}}
+}
+{{end}}{{if .Setter}}
+// {{.Setter}} 写入{{index .Doc 0}}
+{{- range rest .Doc}}
+// {{.}}
+{{- end}}
+func (d *{{$.Type}}) {{.Setter}}(v {{.SetType}}) error {
+{{- range lines .Write}}
+	{{.}}
+{{- end}}
+}
+{{end}}{{end}}
+// {{.Type}}Values 全部可读测点的值
+type {{.Type}}Values struct {
+{{- range .Points}}{{if .Readable}}
+	{{.Name}} {{.GoType}} // {{index .Doc 0}}
+{{- end}}{{end}}
+}
+
+// ReadAll 读取全部可读测点
+// 地址相近的测点合并为一次读取
+func (d *{{.Type}}) ReadAll() (*{{.Type}}Values, error) {
+	v := new({{.Type}}Values)
+{{- if .RegBlock}}
+	var b []byte
+{{- end}}
+{{- if .BitBlock}}
+	var bits []bool
+{{- end}}
+{{- if .Blocks}}
+	var err error
+{{- end}}
+{{- range .Blocks}}
+
+	// {{.Desc}}
+{{- if .Bit}}
+	bits, err = d.readBits({{.Area}}, {{.Offset}}, {{.Num}})
+	if err != nil {
+		return nil, err
+	}
+{{- range .Items}}
+	v.{{.Point.Name}} = bits[{{.Start}}]
+{{- end}}
+{{- else}}
+	b, err = d.readRegs({{.Area}}, {{.Offset}}, {{.Num}})
+	if err != nil {
+		return nil, err
+	}
+{{- range .Items}}
+	v.{{.Point.Name}}, err = {{.Point.Decode}}(b[{{.Start}}:{{.End}}])
+	if err != nil {
+		return nil, err
+	}
+{{- end}}
+{{- end}}
+{{- end}}
+	return v, nil
+}
+`))
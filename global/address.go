@@ -0,0 +1,185 @@
+package global
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Address 数据区和从0开始的偏移量
+type Address struct {
+	Area   Area
+	Offset uint16
+}
+
+// AddressStyle 地址的书写格式
+type AddressStyle byte
+
+// 支持的地址格式
+const (
+	AddressPrefix   AddressStyle = iota // 数据区前缀加从0开始的偏移量，如HR:100
+	AddressModicon5                     // 5位Modicon地址，从1开始，如40101
+	AddressModicon6                     // 6位Modicon地址，从1开始，如400101
+	AddressModiconX                     // 带x的Modicon地址，从1开始，如4x0101
+	AddressIEC                          // IEC 61131-3地址，从0开始，如%MW100
+)
+
+// Modicon地址的首位数字
+var modiconAreas = map[byte]Area{
+	'0': AreaCoil,
+	'1': AreaDiscreteInput,
+	'3': AreaInputRegister,
+	'4': AreaHoldingRegister,
+}
+
+// IEC地址的前缀，较长的前缀在前
+var iecAreas = []struct {
+	prefix string
+	area   Area
+}{
+	{"MW", AreaHoldingRegister},
+	{"IW", AreaInputRegister},
+	{"M", AreaCoil},
+	{"I", AreaDiscreteInput},
+}
+
+// ParseAddress 解析地址
+// 支持以下格式，不区分大小写：
+//
+//	40001、400001：5位或6位Modicon地址，首位为数据区（0线圈、1离散输入、3输入寄存器、4保持寄存器），其余为从1开始的编号
+//	4x0001、3x10：首位为数据区，x之后为从1开始的编号
+//	%MW100、%IW100、%M100、%I100：IEC地址，分别对应保持寄存器、输入寄存器、线圈和离散输入，编号从0开始
+//	HR:100、hr100：数据区名称（同`ParseArea`）加从0开始的偏移量
+func ParseAddress(s string) (Address, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Address{}, fmt.Errorf("empty address")
+	}
+
+	// IEC地址
+	if s[0] == '%' {
+		rest := strings.ToUpper(s[1:])
+		for _, item := range iecAreas {
+			if strings.HasPrefix(rest, item.prefix) {
+				offset, err := parseOffset(rest[len(item.prefix):], 0, 0xffff)
+				if err != nil {
+					return Address{}, fmt.Errorf("invalid address `%s`: %v", s, err)
+				}
+				return Address{Area: item.area, Offset: uint16(offset)}, nil
+			}
+		}
+		return Address{}, fmt.Errorf("invalid address `%s`", s)
+	}
+
+	// Modicon地址
+	if area, ok := modiconAreas[s[0]]; ok && len(s) > 1 {
+		var num string
+		var max uint64
+		switch {
+		case s[1] == 'x' || s[1] == 'X':
+			num, max = s[2:], 65536
+		case isDigits(s) && len(s) == 5:
+			num, max = s[1:], 9999
+		case isDigits(s) && len(s) == 6:
+			num, max = s[1:], 65536
+		}
+		if num != "" {
+			n, err := parseOffset(num, 1, max)
+			if err != nil {
+				return Address{}, fmt.Errorf("invalid address `%s`: %v", s, err)
+			}
+			return Address{Area: area, Offset: uint16(n - 1)}, nil
+		}
+		if isDigits(s) {
+			return Address{}, fmt.Errorf("invalid address `%s`: modicon address requires 5 or 6 digits", s)
+		}
+	}
+
+	// 数据区名称加偏移量
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return r == ':' || (r >= '0' && r <= '9')
+	})
+	if i <= 0 {
+		return Address{}, fmt.Errorf("invalid address `%s`", s)
+	}
+	area, err := ParseArea(s[:i])
+	if err != nil {
+		return Address{}, fmt.Errorf("invalid address `%s`: %v", s, err)
+	}
+	offset, err := parseOffset(strings.TrimPrefix(s[i:], ":"), 0, 0xffff)
+	if err != nil {
+		return Address{}, fmt.Errorf("invalid address `%s`: %v", s, err)
+	}
+	return Address{Area: area, Offset: uint16(offset)}, nil
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// 解析十进制编号并检查范围
+func parseOffset(s string, min, max uint64) (uint64, error) {
+	if s == "" || !isDigits(s) {
+		return 0, fmt.Errorf("invalid number `%s`", s)
+	}
+	n, err := strconv.ParseUint(s, 10, 32)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("number %s out of range %d~%d", s, min, max)
+	}
+	return n, nil
+}
+
+// FunCode 读取地址所在数据区的功能码
+func (a Address) FunCode() FunCode {
+	return a.Area.ReadFunCode()
+}
+
+// Format 按指定格式书写地址
+// 5位Modicon地址只能表示前9999个地址，超出时返回错误
+func (a Address) Format(style AddressStyle) (string, error) {
+	var digit byte
+	for d, area := range modiconAreas {
+		if area == a.Area {
+			digit = d
+		}
+	}
+	if digit == 0 {
+		return "", fmt.Errorf("invalid area %s", a.Area)
+	}
+
+	num := uint32(a.Offset) + 1
+	switch style {
+	case AddressPrefix:
+		return fmt.Sprintf("%s:%d", strings.ToUpper(a.Area.String()), a.Offset), nil
+	case AddressModicon5:
+		if num > 9999 {
+			return "", fmt.Errorf("offset %d cannot be written as 5-digit address", a.Offset)
+		}
+		return fmt.Sprintf("%c%04d", digit, num), nil
+	case AddressModicon6:
+		return fmt.Sprintf("%c%05d", digit, num), nil
+	case AddressModiconX:
+		return fmt.Sprintf("%cx%04d", digit, num), nil
+	case AddressIEC:
+		for _, item := range iecAreas {
+			if item.area == a.Area {
+				return fmt.Sprintf("%%%s%d", item.prefix, a.Offset), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("invalid address style %d", style)
+}
+
+// String 数据区前缀格式的地址，如HR:100
+func (a Address) String() string {
+	s, err := a.Format(AddressPrefix)
+	if err != nil {
+		return fmt.Sprintf("Address(%d,%d)", byte(a.Area), a.Offset)
+	}
+	return s
+}
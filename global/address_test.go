@@ -0,0 +1,138 @@
+package global
+
+import "testing"
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Address
+	}{
+		{"40001", Address{AreaHoldingRegister, 0}},
+		{"49999", Address{AreaHoldingRegister, 9998}},
+		{"400001", Address{AreaHoldingRegister, 0}},
+		{"465536", Address{AreaHoldingRegister, 65535}},
+		{"30010", Address{AreaInputRegister, 9}},
+		{"00001", Address{AreaCoil, 0}},
+		{"100005", Address{AreaDiscreteInput, 4}},
+		{"4x0001", Address{AreaHoldingRegister, 0}},
+		{"3x10", Address{AreaInputRegister, 9}},
+		{"4X65536", Address{AreaHoldingRegister, 65535}},
+		{"%MW100", Address{AreaHoldingRegister, 100}},
+		{"%iw0", Address{AreaInputRegister, 0}},
+		{"%M3", Address{AreaCoil, 3}},
+		{"%I7", Address{AreaDiscreteInput, 7}},
+		{"HR:100", Address{AreaHoldingRegister, 100}},
+		{"hr100", Address{AreaHoldingRegister, 100}},
+		{"coil:0", Address{AreaCoil, 0}},
+		{" di:65535 ", Address{AreaDiscreteInput, 65535}},
+	}
+	for _, tt := range tests {
+		got, err := ParseAddress(tt.in)
+		if err != nil {
+			t.Errorf("ParseAddress(%q) error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAddress(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseAddressInvalid(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"40000",   // Modicon编号从1开始
+		"400000",  // Modicon编号从1开始
+		"465537",  // 超出地址范围
+		"4000",    // 位数不对
+		"4000001", // 位数不对
+		"20001",   // 没有2开头的数据区
+		"4x",      // 缺少编号
+		"4x0",     // 编号从1开始
+		"%QW1",    // 不支持的IEC前缀
+		"%MW",     // 缺少编号
+		"%MW65536",
+		"HR:",
+		"HR:-1",
+		"XX:1",
+		":100",
+	} {
+		if a, err := ParseAddress(in); err == nil {
+			t.Errorf("ParseAddress(%q) = %v, want error", in, a)
+		}
+	}
+}
+
+func TestAddressFormat(t *testing.T) {
+	tests := []struct {
+		a     Address
+		style AddressStyle
+		want  string
+	}{
+		{Address{AreaHoldingRegister, 0}, AddressModicon5, "40001"},
+		{Address{AreaHoldingRegister, 100}, AddressModicon6, "400101"},
+		{Address{AreaInputRegister, 9}, AddressModiconX, "3x0010"},
+		{Address{AreaCoil, 0}, AddressModicon5, "00001"},
+		{Address{AreaHoldingRegister, 100}, AddressIEC, "%MW100"},
+		{Address{AreaDiscreteInput, 5}, AddressIEC, "%I5"},
+		{Address{AreaHoldingRegister, 100}, AddressPrefix, "HR:100"},
+		{Address{AreaCoil, 3}, AddressPrefix, "COIL:3"},
+	}
+	for _, tt := range tests {
+		got, err := tt.a.Format(tt.style)
+		if err != nil {
+			t.Errorf("%v.Format(%d) error: %v", tt.a, tt.style, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%v.Format(%d) = %q, want %q", tt.a, tt.style, got, tt.want)
+		}
+	}
+
+	if s, err := (Address{AreaHoldingRegister, 9999}).Format(AddressModicon5); err == nil {
+		t.Errorf("offset 9999 formatted as 5-digit address %q, want error", s)
+	}
+	if s, err := (Address{Area: 0}).Format(AddressPrefix); err == nil {
+		t.Errorf("invalid area formatted as %q, want error", s)
+	}
+}
+
+// 各种格式书写后应能解析回同一地址
+func TestAddressRoundTrip(t *testing.T) {
+	styles := []AddressStyle{AddressPrefix, AddressModicon6, AddressModiconX, AddressIEC}
+	for _, area := range []Area{AreaCoil, AreaDiscreteInput, AreaInputRegister, AreaHoldingRegister} {
+		for _, offset := range []uint16{0, 1, 9998, 9999, 65535} {
+			a := Address{area, offset}
+			for _, style := range styles {
+				s, err := a.Format(style)
+				if err != nil {
+					t.Fatalf("%v.Format(%d) error: %v", a, style, err)
+				}
+				got, err := ParseAddress(s)
+				if err != nil || got != a {
+					t.Errorf("ParseAddress(%q) = %v, %v, want %v", s, got, err, a)
+				}
+			}
+		}
+	}
+}
+
+func TestAddressFunCode(t *testing.T) {
+	tests := map[string]FunCode{
+		"00001":  ReadCoils,
+		"10001":  ReadInputs,
+		"30001":  ReadInputRegisters,
+		"40001":  ReadHoldingRegisters,
+		"%MW0":   ReadHoldingRegisters,
+		"ir:100": ReadInputRegisters,
+	}
+	for in, want := range tests {
+		a, err := ParseAddress(in)
+		if err != nil {
+			t.Fatalf("ParseAddress(%q) error: %v", in, err)
+		}
+		if got := a.FunCode(); got != want {
+			t.Errorf("ParseAddress(%q).FunCode() = %#x, want %#x", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package global
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Area modbus数据区
+type Area byte
+
+// 四个modbus数据区
+const (
+	AreaCoil            Area = iota + 1 // 线圈
+	AreaDiscreteInput                   // 离散输入
+	AreaInputRegister                   // 输入寄存器
+	AreaHoldingRegister                 // 保持寄存器
+)
+
+var areaNames = map[string]Area{
+	"coil": AreaCoil,
+	"co":   AreaCoil,
+	"di":   AreaDiscreteInput,
+	"ir":   AreaInputRegister,
+	"hr":   AreaHoldingRegister,
+}
+
+// ParseArea 解析数据区的名称
+// 支持coil（co）、di、ir和hr，不区分大小写
+func ParseArea(s string) (Area, error) {
+	a, ok := areaNames[strings.ToLower(s)]
+	if !ok {
+		return 0, fmt.Errorf("invalid area `%s`", s)
+	}
+	return a, nil
+}
+
+func (a Area) String() string {
+	switch a {
+	case AreaCoil:
+		return "coil"
+	case AreaDiscreteInput:
+		return "di"
+	case AreaInputRegister:
+		return "ir"
+	case AreaHoldingRegister:
+		return "hr"
+	default:
+		return fmt.Sprintf("Area(%d)", byte(a))
+	}
+}
+
+// IsBit 数据区是否按位访问
+func (a Area) IsBit() bool {
+	return a == AreaCoil || a == AreaDiscreteInput
+}
+
+// Writable 数据区是否可写
+func (a Area) Writable() bool {
+	return a == AreaCoil || a == AreaHoldingRegister
+}
+
+// ReadFunCode 读取数据区的功能码
+func (a Area) ReadFunCode() FunCode {
+	switch a {
+	case AreaCoil:
+		return ReadCoils
+	case AreaDiscreteInput:
+		return ReadInputs
+	case AreaInputRegister:
+		return ReadInputRegisters
+	default:
+		return ReadHoldingRegisters
+	}
+}
+
+// MaxReadNum 单次读取数据区的最大个数
+// 线圈和离散输入为2000个，寄存器为125个
+func (a Area) MaxReadNum() uint16 {
+	if a.IsBit() {
+		return 2000
+	}
+	return 125
+}
+
+// MaxWriteNum 单次写多个线圈或寄存器的最大个数
+// 线圈为1968个，寄存器为123个
+func (a Area) MaxWriteNum() uint16 {
+	if a.IsBit() {
+		return 1968
+	}
+	return 123
+}
+
+// ReadArea 读取功能码对应的数据区
+// 不是0x01~0x04时返回错误
+func ReadArea(fun FunCode) (Area, error) {
+	switch fun {
+	case ReadCoils:
+		return AreaCoil, nil
+	case ReadInputs:
+		return AreaDiscreteInput, nil
+	case ReadInputRegisters:
+		return AreaInputRegister, nil
+	case ReadHoldingRegisters:
+		return AreaHoldingRegister, nil
+	default:
+		return 0, fmt.Errorf("function code %#02x is not a read function", fun)
+	}
+}
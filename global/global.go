@@ -1,5 +1,10 @@
 package global
 
+import (
+	"fmt"
+	"sync"
+)
+
 // FunCode modbus功能码类型别名
 type FunCode = byte
 
@@ -11,8 +16,34 @@ const (
 	ReadInputRegisters   FunCode = 0x04
 	WriteSingleCoil      FunCode = 0x05
 	WriteSingleRegister  FunCode = 0x06
+	ReadExceptionStatus  FunCode = 0x07
+	Diagnostics          FunCode = 0x08
+	GetCommEventCounter  FunCode = 0x0b
+	GetCommEventLog      FunCode = 0x0c
 	WriteMultiCoils      FunCode = 0x0f
 	WriteMultiRegisters  FunCode = 0x10
+	ReadFileRecord       FunCode = 0x14
+	WriteFileRecord      FunCode = 0x15
+)
+
+// DiagSubFunCode 诊断（0x08）子功能码类型别名
+type DiagSubFunCode = uint16
+
+// 支持的诊断子功能码
+const (
+	DiagReturnQueryData              DiagSubFunCode = 0x00
+	DiagRestartCommunications        DiagSubFunCode = 0x01
+	DiagReturnDiagnosticRegister     DiagSubFunCode = 0x02
+	DiagForceListenOnlyMode          DiagSubFunCode = 0x04
+	DiagClearCounters                DiagSubFunCode = 0x0a
+	DiagReturnBusMessageCount        DiagSubFunCode = 0x0b
+	DiagReturnBusCommErrorCount      DiagSubFunCode = 0x0c
+	DiagReturnBusExceptionErrorCount DiagSubFunCode = 0x0d
+	DiagReturnSlaveMessageCount      DiagSubFunCode = 0x0e
+	DiagReturnSlaveNoResponseCount   DiagSubFunCode = 0x0f
+	DiagReturnSlaveNAKCount          DiagSubFunCode = 0x10
+	DiagReturnSlaveBusyCount         DiagSubFunCode = 0x11
+	DiagReturnBusCharOverrunCount    DiagSubFunCode = 0x12
 )
 
 // SlaveError modbus从站异常类型
@@ -33,6 +64,53 @@ var SlaveErrorMap = map[byte]SlaveError{
 	0x04: {0x04, "slave device failure"},
 	0x05: {0x05, "acknowledge"},
 	0x06: {0x06, "slave device busy"},
+	0x08: {0x08, "memory parity error"},
 	0x0a: {0x0a, "gateway path unavailable"},
 	0x0b: {0x0b, "gateway target device failed to respond"},
 }
+
+// ExceptionTable 从站异常码表
+// 在标准异常码的基础上，可以为某类设备注册厂家自定义的异常码
+type ExceptionTable struct {
+	l    *sync.RWMutex
+	msgs map[byte]string
+}
+
+// NewExceptionTable 构造函数
+// 包含全部标准异常码，`ext` 中的异常码会覆盖或扩展标准异常码
+func NewExceptionTable(ext map[byte]string) *ExceptionTable {
+	t := &ExceptionTable{
+		l:    new(sync.RWMutex),
+		msgs: make(map[byte]string, len(SlaveErrorMap)+len(ext)),
+	}
+	for code, e := range SlaveErrorMap {
+		t.msgs[code] = e.msg
+	}
+	for code, msg := range ext {
+		t.msgs[code] = msg
+	}
+	return t
+}
+
+// Register 注册异常码的描述
+func (t *ExceptionTable) Register(code byte, msg string) {
+	t.l.Lock()
+	defer t.l.Unlock()
+	t.msgs[code] = msg
+}
+
+// Lookup 根据异常码得到从站异常
+// 未注册的异常码也会返回带有异常码的从站异常
+func (t *ExceptionTable) Lookup(code byte) SlaveError {
+	t.l.RLock()
+	defer t.l.RUnlock()
+	msg, ok := t.msgs[code]
+	if !ok {
+		msg = fmt.Sprintf("unknown error code `%x`", code)
+	}
+	return SlaveError{Code: code, msg: msg}
+}
+
+// DefaultExceptionTable 默认的异常码表
+// 未单独配置异常码表的从站使用这个表
+var DefaultExceptionTable = NewExceptionTable(nil)
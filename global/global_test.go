@@ -0,0 +1,36 @@
+package global
+
+import "testing"
+
+func TestExceptionTable(t *testing.T) {
+	et := NewExceptionTable(map[byte]string{
+		0x04: "meter fault",
+		0x80: "calibration locked",
+	})
+	et.Register(0x81, "password required")
+
+	tests := []struct {
+		code byte
+		want string
+	}{
+		{0x02, "illegal data address"},
+		{0x04, "meter fault"},
+		{0x80, "calibration locked"},
+		{0x81, "password required"},
+		{0x7f, "unknown error code `7f`"},
+	}
+	for _, tt := range tests {
+		e := et.Lookup(tt.code)
+		if e.Code != tt.code || e.Error() != tt.want {
+			t.Errorf("Lookup(%#x) = %#x %q, want %q", tt.code, e.Code, e.Error(), tt.want)
+		}
+	}
+
+	// 自定义异常码不影响默认表
+	if got := DefaultExceptionTable.Lookup(0x04).Error(); got != "slave device failure" {
+		t.Errorf("default Lookup(0x04) = %q, want slave device failure", got)
+	}
+	if got := DefaultExceptionTable.Lookup(0x80).Error(); got != "unknown error code `80`" {
+		t.Errorf("default Lookup(0x80) = %q", got)
+	}
+}
@@ -1,8 +1,11 @@
 module ckklearn.com/testmodbus
 
-go 1.15
+go 1.17
 
 require (
 	github.com/tarm/serial v0.0.0-20180830185346-98f6abe2eb07
-	golang.org/x/sys v0.0.0-20200824131525-c12d262b63d8 // indirect
+	golang.org/x/text v0.13.0
+	gopkg.in/yaml.v2 v2.4.0
 )
+
+require golang.org/x/sys v0.5.0 // indirect
@@ -26,8 +26,20 @@ const (
 var master *mbrtu.RtuMaster
 
 // Open 打开串口
+// `readTimeout` 的单位为秒，需要更短的超时请使用 `OpenMs`
 //export Open
 func Open(name *C.char, baud C.uint, dBits C.uchar, parity C.char, sBits C.uchar, readTimeout C.uint, errMsg *C.uchar) C.int {
+	return open(name, baud, dBits, parity, sBits, time.Second*time.Duration(readTimeout), errMsg)
+}
+
+// OpenMs 打开串口
+// `readTimeoutMs` 的单位为毫秒，linux下串口超时的精度为100毫秒
+//export OpenMs
+func OpenMs(name *C.char, baud C.uint, dBits C.uchar, parity C.char, sBits C.uchar, readTimeoutMs C.uint, errMsg *C.uchar) C.int {
+	return open(name, baud, dBits, parity, sBits, time.Millisecond*time.Duration(readTimeoutMs), errMsg)
+}
+
+func open(name *C.char, baud C.uint, dBits C.uchar, parity C.char, sBits C.uchar, readTimeout time.Duration, errMsg *C.uchar) C.int {
 	if master != nil {
 		writeString(errMsg, errNotClose)
 		return retErr
@@ -68,7 +80,7 @@ func Open(name *C.char, baud C.uint, dBits C.uchar, parity C.char, sBits C.uchar
 		Size:        byte(dBits),
 		Parity:      _parity,
 		StopBits:    _stopBits,
-		ReadTimeout: time.Second * time.Duration(readTimeout),
+		ReadTimeout: readTimeout,
 	})
 	if err != nil {
 		writeString(errMsg, err.Error())
@@ -109,7 +121,7 @@ func ReadHoldingRegisters(data *C.uchar, addr C.uchar, offset, num C.ushort, crc
 		return retErr
 	}
 
-	p := make([]byte, 1024)
+	p := make([]byte, int(num)*2)
 	n, err := master.ReadHoldingRegisters(p, byte(addr), uint16(offset), uint16(num), _crcOrder)
 	if err != nil {
 		writeString(errMsg, err.Error())
@@ -202,6 +214,26 @@ func NRWriteMultiRegisters(data *C.ushort, dataLen C.int, addr C.uchar, offset C
 	return 0
 }
 
+// SetAdaptiveTimeout 配置自适应超时
+// 超时取往返延时的`percentile`分位数加上`marginMs`毫秒，限制在`floorMs`和`ceilingMs`毫秒之间，`percentile` 为0时关闭
+//export SetAdaptiveTimeout
+func SetAdaptiveTimeout(percentile C.double, marginMs, floorMs, ceilingMs, minSamples C.uint, errMsg *C.uchar) C.int {
+	if master == nil {
+		writeString(errMsg, errNotOpen)
+		return retErr
+	}
+
+	master.SetTimeoutConfig(mbrtu.TimeoutConfig{
+		Percentile: float64(percentile),
+		Margin:     time.Millisecond * time.Duration(marginMs),
+		Floor:      time.Millisecond * time.Duration(floorMs),
+		Ceiling:    time.Millisecond * time.Duration(ceilingMs),
+		MinSamples: int(minSamples),
+	})
+
+	return 0
+}
+
 // ---- 功能函数 ----
 
 func write(dst *C.uchar, src []byte, asString bool) {
@@ -231,6 +263,8 @@ func getCrcOrder(o C.char) (binary.ByteOrder, error) {
 		return binary.BigEndian, nil
 	case '<':
 		return binary.LittleEndian, nil
+	case 'a':
+		return mbrtu.AutoCrcOrder, nil
 	default:
 		return nil, fmt.Errorf(errInvalidCrcOrder)
 	}
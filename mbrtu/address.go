@@ -0,0 +1,34 @@
+package mbrtu
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"ckklearn.com/testmodbus/global"
+)
+
+// ReadAddress 按地址读取
+// `address` 的格式见`global.ParseAddress`，如40001、3x0010、%MW100、HR:100
+func (m *RtuMaster) ReadAddress(p []byte, addr byte, address string, num uint16, crcOrder binary.ByteOrder) (int, error) {
+	a, err := global.ParseAddress(address)
+	if err != nil {
+		return 0, err
+	}
+	return m.ReadArea(p, addr, a.Area, a.Offset, num, crcOrder)
+}
+
+// WriteAddress 按地址写线圈或保持寄存器
+// 线圈的值非0为闭合，只有1个值时使用写单个的功能码
+func (m *RtuMaster) WriteAddress(addr byte, address string, data []uint16, crcOrder binary.ByteOrder) error {
+	a, err := global.ParseAddress(address)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("no data to write")
+	}
+	if !a.Area.Writable() {
+		return fmt.Errorf("area %s is read-only", a.Area)
+	}
+	return m.writeRun(addr, a.Area, a.Offset, data, crcOrder)
+}
@@ -0,0 +1,113 @@
+package mbrtu
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"ckklearn.com/testmodbus/global"
+)
+
+// WriteItem 批量写入的一项
+type WriteItem struct {
+	Offset uint16
+	Value  uint16 // 线圈的值非0为闭合
+}
+
+// 地址连续的一段写入
+type writeRun struct {
+	offset uint16
+	values []uint16
+	items  [][]int // 每个地址对应的写入项在输入中的序号
+}
+
+// WriteBatch 批量写线圈或保持寄存器
+// 地址连续的项合并为写多个线圈或写多个寄存器请求，单次写入不超过数据区的上限；
+// 从站的协议变体不支持写多个时逐个写入。同一地址出现多次时写入最后一个值。
+// 返回与`items`一一对应的结果，任一项失败时同时返回第一个错误
+func (m *RtuMaster) WriteBatch(addr byte, area global.Area, items []WriteItem, crcOrder binary.ByteOrder) ([]error, error) {
+	if area != global.AreaCoil && area != global.AreaHoldingRegister {
+		return nil, fmt.Errorf("area %s is read-only", area)
+	}
+
+	runs := planWriteRuns(items, area.MaxWriteNum())
+
+	res := make([]error, len(items))
+	var first error
+	set := func(ids []int, err error) {
+		for _, i := range ids {
+			res[i] = err
+		}
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+
+	multi := !m.dialectFor(addr).NoMultiWrite
+	for _, r := range runs {
+		if multi && len(r.values) > 1 {
+			err := m.writeRun(addr, area, r.offset, r.values, crcOrder)
+			for _, ids := range r.items {
+				set(ids, err)
+			}
+			continue
+		}
+		for j, v := range r.values {
+			err := m.writeRun(addr, area, r.offset+uint16(j), []uint16{v}, crcOrder)
+			set(r.items[j], err)
+		}
+	}
+	return res, first
+}
+
+// 写入一段地址连续的值，单个值使用写单个的功能码
+func (m *RtuMaster) writeRun(addr byte, area global.Area, offset uint16, values []uint16, crcOrder binary.ByteOrder) error {
+	if area == global.AreaCoil {
+		if len(values) == 1 {
+			return m.WriteSingleCoil(addr, offset, values[0] != 0, crcOrder)
+		}
+		on := make([]bool, len(values))
+		for i, v := range values {
+			on[i] = v != 0
+		}
+		return m.WriteMultiCoils(addr, offset, on, crcOrder)
+	}
+	if len(values) == 1 {
+		return m.WriteSingleRegister(addr, offset, values[0], crcOrder)
+	}
+	return m.WriteMultiRegisters(addr, offset, values, crcOrder)
+}
+
+// 将写入项按地址分段
+// 地址连续的项合并为一段，每段不超过`max`个地址，同一地址保留最后一个值
+func planWriteRuns(items []WriteItem, max uint16) []*writeRun {
+	idx := make([]int, len(items))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool {
+		return items[idx[i]].Offset < items[idx[j]].Offset
+	})
+
+	var runs []*writeRun
+	var cur *writeRun
+	for _, i := range idx {
+		it := items[i]
+		if cur != nil {
+			last := int(cur.offset) + len(cur.values) - 1
+			if int(it.Offset) == last {
+				cur.values[len(cur.values)-1] = it.Value
+				cur.items[len(cur.items)-1] = append(cur.items[len(cur.items)-1], i)
+				continue
+			}
+			if int(it.Offset) == last+1 && len(cur.values) < int(max) {
+				cur.values = append(cur.values, it.Value)
+				cur.items = append(cur.items, []int{i})
+				continue
+			}
+		}
+		cur = &writeRun{offset: it.Offset, values: []uint16{it.Value}, items: [][]int{{i}}}
+		runs = append(runs, cur)
+	}
+	return runs
+}
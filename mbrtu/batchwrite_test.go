@@ -0,0 +1,54 @@
+package mbrtu
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlanWriteRuns(t *testing.T) {
+	type run struct {
+		offset uint16
+		values []uint16
+		items  [][]int
+	}
+	tests := []struct {
+		name  string
+		items []WriteItem
+		max   uint16
+		want  []run
+	}{
+		{"empty", nil, 123, nil},
+		{"adjacent", []WriteItem{{12, 3}, {10, 1}, {11, 2}}, 123, []run{
+			{10, []uint16{1, 2, 3}, [][]int{{1}, {2}, {0}}},
+		}},
+		{"gap", []WriteItem{{10, 1}, {12, 3}}, 123, []run{
+			{10, []uint16{1}, [][]int{{0}}},
+			{12, []uint16{3}, [][]int{{1}}},
+		}},
+		// 同一地址保留最后一个值，结果对应所有的项
+		{"duplicate", []WriteItem{{5, 1}, {6, 2}, {5, 9}, {5, 7}}, 123, []run{
+			{5, []uint16{7, 2}, [][]int{{0, 2, 3}, {1}}},
+		}},
+		{"limit", []WriteItem{{0, 0}, {1, 1}, {2, 2}, {3, 3}, {4, 4}}, 2, []run{
+			{0, []uint16{0, 1}, [][]int{{0}, {1}}},
+			{2, []uint16{2, 3}, [][]int{{2}, {3}}},
+			{4, []uint16{4}, [][]int{{4}}},
+		}},
+		{"duplicate at limit", []WriteItem{{0, 0}, {1, 1}, {1, 5}, {2, 2}}, 2, []run{
+			{0, []uint16{0, 5}, [][]int{{0}, {1, 2}}},
+			{2, []uint16{2}, [][]int{{3}}},
+		}},
+		{"address end", []WriteItem{{0xffff, 1}, {0xfffe, 2}}, 123, []run{
+			{0xfffe, []uint16{2, 1}, [][]int{{1}, {0}}},
+		}},
+	}
+	for _, tt := range tests {
+		var got []run
+		for _, r := range planWriteRuns(tt.items, tt.max) {
+			got = append(got, run{r.offset, r.values, r.items})
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: runs = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package mbrtu
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"ckklearn.com/testmodbus/global"
+	"ckklearn.com/testmodbus/mbrtu/mbcodec"
+)
+
+// Span 从站某个数据区中一段连续的地址
+type Span struct {
+	Slave  byte
+	Fun    global.FunCode // 读取功能码，0x01~0x04
+	Offset uint16
+	Num    uint16 // 个数，为0时视为1
+}
+
+// 个数为0时视为1
+func (s Span) normalize() Span {
+	if s.Num == 0 {
+		s.Num = 1
+	}
+	return s
+}
+
+// 地址段的结束位置（不含）
+func (s Span) end() int {
+	return int(s.Offset) + int(s.Num)
+}
+
+// 两个地址段是否属于同一从站的同一数据区
+func (s Span) sameArea(o Span) bool {
+	return s.Slave == o.Slave && s.Fun == o.Fun
+}
+
+// 是否与[start, end)有重叠
+func (s Span) overlaps(start, end int) bool {
+	return int(s.Offset) < end && start < s.end()
+}
+
+// CoalesceOptions 合并读请求的选项
+type CoalesceOptions struct {
+	MaxGap    uint16 // 为合并请求而一并读取的未请求地址的最大个数
+	MaxNum    uint16 // 单次读取的最大个数，为0或超过数据区的上限时使用数据区的上限
+	Forbidden []Span // 不能读取的地址，合并后的请求不会跨越这些地址
+}
+
+// ReadBlock 合并后的读请求
+type ReadBlock struct {
+	Span
+	Wants []int // 请求覆盖的地址段在输入中的序号
+}
+
+// Coalesce 将需要读取的地址段合并为最少的读请求
+// 同一从站同一功能码的地址段，间隔不超过`MaxGap`、间隔中没有禁止读取的地址、且合并后不超过单次读取的上限时合并
+// 地址段本身超过上限或包含禁止读取的地址时返回错误
+func Coalesce(wants []Span, opt CoalesceOptions) ([]ReadBlock, error) {
+	forbidden := make([]Span, len(opt.Forbidden))
+	for i, f := range opt.Forbidden {
+		forbidden[i] = f.normalize()
+	}
+	spans := make([]Span, len(wants))
+	limits := make(map[global.FunCode]int)
+	for i, w := range wants {
+		area, err := global.ReadArea(w.Fun)
+		if err != nil {
+			return nil, err
+		}
+		limit := int(area.MaxReadNum())
+		if opt.MaxNum > 0 && int(opt.MaxNum) < limit {
+			limit = int(opt.MaxNum)
+		}
+		limits[w.Fun] = limit
+
+		w = w.normalize()
+		if w.end() > 0x10000 {
+			return nil, fmt.Errorf("span %d: offset %d + num %d exceeds address space", i, w.Offset, w.Num)
+		}
+		if int(w.Num) > limit {
+			return nil, fmt.Errorf("span %d: num %d exceeds limit %d", i, w.Num, limit)
+		}
+		for _, f := range forbidden {
+			if f.sameArea(w) && f.overlaps(int(w.Offset), w.end()) {
+				return nil, fmt.Errorf("span %d: overlaps forbidden address %d", i, f.Offset)
+			}
+		}
+		spans[i] = w
+	}
+
+	idx := make([]int, len(spans))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool {
+		a, b := spans[idx[i]], spans[idx[j]]
+		if a.Slave != b.Slave {
+			return a.Slave < b.Slave
+		}
+		if a.Fun != b.Fun {
+			return a.Fun < b.Fun
+		}
+		return a.Offset < b.Offset
+	})
+
+	// 按起始地址依次尽可能延长当前请求，得到的请求个数最少
+	var blocks []ReadBlock
+	var cur *ReadBlock
+	for _, i := range idx {
+		w := spans[i]
+		if cur != nil && cur.sameArea(w) && w.end()-int(cur.Offset) <= limits[w.Fun] {
+			gapStart, gapEnd := cur.end(), int(w.Offset)
+			ok := gapEnd-gapStart <= int(opt.MaxGap)
+			if ok && gapEnd > gapStart {
+				for _, f := range forbidden {
+					if f.sameArea(w) && f.overlaps(gapStart, gapEnd) {
+						ok = false
+						break
+					}
+				}
+			}
+			if ok {
+				if w.end() > cur.end() {
+					cur.Num = uint16(w.end() - int(cur.Offset))
+				}
+				cur.Wants = append(cur.Wants, i)
+				continue
+			}
+		}
+		blocks = append(blocks, ReadBlock{Span: w, Wants: []int{i}})
+		cur = &blocks[len(blocks)-1]
+	}
+	return blocks, nil
+}
+
+// ReadSpans 合并读取多个地址段
+// 按`Coalesce`合并后依次读取，返回与`wants`一一对应的数据，线圈和离散输入为按位打包的字节
+// 任一请求失败时返回错误
+func (m *RtuMaster) ReadSpans(wants []Span, opt CoalesceOptions, crcOrder binary.ByteOrder) ([][]byte, error) {
+	blocks, err := Coalesce(wants, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([][]byte, len(wants))
+	for _, blk := range blocks {
+		area, _ := global.ReadArea(blk.Fun)
+		p, err := m.ReadAreaBytes(blk.Slave, area, blk.Offset, blk.Num, crcOrder)
+		if err != nil {
+			return nil, err
+		}
+
+		var bits []bool
+		if area.IsBit() {
+			bits, err = mbcodec.Bools(p, int(blk.Num))
+			if err != nil {
+				return nil, err
+			}
+		}
+		for _, i := range blk.Wants {
+			w := wants[i].normalize()
+			start := int(w.Offset - blk.Offset)
+			if area.IsBit() {
+				res[i] = mbcodec.EncodeBools(bits[start : start+int(w.Num)])
+			} else {
+				res[i] = append([]byte(nil), p[start*2:(start+int(w.Num))*2]...)
+			}
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,138 @@
+package mbrtu
+
+import (
+	"reflect"
+	"testing"
+
+	"ckklearn.com/testmodbus/global"
+)
+
+func hr(offset, num uint16) Span {
+	return Span{Slave: 1, Fun: global.ReadHoldingRegisters, Offset: offset, Num: num}
+}
+
+func TestCoalesce(t *testing.T) {
+	tests := []struct {
+		name  string
+		wants []Span
+		opt   CoalesceOptions
+		want  []ReadBlock
+	}{
+		{
+			name:  "adjacent",
+			wants: []Span{hr(0, 2), hr(2, 2), hr(4, 1)},
+			want:  []ReadBlock{{Span: hr(0, 5), Wants: []int{0, 1, 2}}},
+		},
+		{
+			name:  "overlapping",
+			wants: []Span{hr(0, 4), hr(2, 1)},
+			want:  []ReadBlock{{Span: hr(0, 4), Wants: []int{0, 1}}},
+		},
+		{
+			name:  "num 0 as 1",
+			wants: []Span{hr(0, 0), hr(1, 0)},
+			want:  []ReadBlock{{Span: hr(0, 2), Wants: []int{0, 1}}},
+		},
+		{
+			name:  "unsorted input",
+			wants: []Span{hr(10, 1), hr(0, 1), hr(5, 1)},
+			opt:   CoalesceOptions{MaxGap: 4},
+			want:  []ReadBlock{{Span: hr(0, 11), Wants: []int{1, 2, 0}}},
+		},
+		{
+			name:  "gap too large",
+			wants: []Span{hr(0, 1), hr(4, 1)},
+			opt:   CoalesceOptions{MaxGap: 2},
+			want:  []ReadBlock{{Span: hr(0, 1), Wants: []int{0}}, {Span: hr(4, 1), Wants: []int{1}}},
+		},
+		{
+			name:  "gap allowed",
+			wants: []Span{hr(0, 1), hr(4, 1)},
+			opt:   CoalesceOptions{MaxGap: 3},
+			want:  []ReadBlock{{Span: hr(0, 5), Wants: []int{0, 1}}},
+		},
+		{
+			name:  "forbidden in gap",
+			wants: []Span{hr(0, 1), hr(4, 1)},
+			opt:   CoalesceOptions{MaxGap: 10, Forbidden: []Span{hr(2, 1)}},
+			want:  []ReadBlock{{Span: hr(0, 1), Wants: []int{0}}, {Span: hr(4, 1), Wants: []int{1}}},
+		},
+		{
+			name:  "forbidden num 0 in gap",
+			wants: []Span{hr(0, 1), hr(4, 1)},
+			opt:   CoalesceOptions{MaxGap: 10, Forbidden: []Span{hr(3, 0)}},
+			want:  []ReadBlock{{Span: hr(0, 1), Wants: []int{0}}, {Span: hr(4, 1), Wants: []int{1}}},
+		},
+		{
+			name:  "forbidden in other area",
+			wants: []Span{hr(0, 1), hr(4, 1)},
+			opt: CoalesceOptions{MaxGap: 10, Forbidden: []Span{
+				{Slave: 1, Fun: global.ReadInputRegisters, Offset: 2},
+				{Slave: 2, Fun: global.ReadHoldingRegisters, Offset: 2},
+			}},
+			want: []ReadBlock{{Span: hr(0, 5), Wants: []int{0, 1}}},
+		},
+		{
+			name: "different slave and area",
+			wants: []Span{
+				hr(0, 1),
+				{Slave: 2, Fun: global.ReadHoldingRegisters, Offset: 1, Num: 1},
+				{Slave: 1, Fun: global.ReadInputRegisters, Offset: 1, Num: 1},
+			},
+			want: []ReadBlock{
+				{Span: hr(0, 1), Wants: []int{0}},
+				{Span: Span{Slave: 1, Fun: global.ReadInputRegisters, Offset: 1, Num: 1}, Wants: []int{2}},
+				{Span: Span{Slave: 2, Fun: global.ReadHoldingRegisters, Offset: 1, Num: 1}, Wants: []int{1}},
+			},
+		},
+		{
+			name:  "register limit",
+			wants: []Span{hr(0, 100), hr(100, 30)},
+			want:  []ReadBlock{{Span: hr(0, 100), Wants: []int{0}}, {Span: hr(100, 30), Wants: []int{1}}},
+		},
+		{
+			name:  "MaxNum",
+			wants: []Span{hr(0, 4), hr(4, 4), hr(8, 4)},
+			opt:   CoalesceOptions{MaxNum: 8},
+			want:  []ReadBlock{{Span: hr(0, 8), Wants: []int{0, 1}}, {Span: hr(8, 4), Wants: []int{2}}},
+		},
+		{
+			name: "coil limit",
+			wants: []Span{
+				{Slave: 1, Fun: global.ReadCoils, Offset: 0, Num: 1000},
+				{Slave: 1, Fun: global.ReadCoils, Offset: 1000, Num: 1000},
+			},
+			want: []ReadBlock{{Span: Span{Slave: 1, Fun: global.ReadCoils, Offset: 0, Num: 2000}, Wants: []int{0, 1}}},
+		},
+	}
+	for _, tt := range tests {
+		got, err := Coalesce(tt.wants, tt.opt)
+		if err != nil {
+			t.Errorf("%s: error: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCoalesceInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		wants []Span
+		opt   CoalesceOptions
+	}{
+		{"write function code", []Span{{Slave: 1, Fun: global.WriteMultiRegisters, Num: 1}}, CoalesceOptions{}},
+		{"exceeds limit", []Span{hr(0, 126)}, CoalesceOptions{}},
+		{"exceeds MaxNum", []Span{hr(0, 9)}, CoalesceOptions{MaxNum: 8}},
+		{"exceeds address space", []Span{hr(65535, 2)}, CoalesceOptions{}},
+		{"overlaps forbidden", []Span{hr(0, 4)}, CoalesceOptions{Forbidden: []Span{hr(3, 1)}}},
+		{"forbidden num 0", []Span{hr(10, 0)}, CoalesceOptions{Forbidden: []Span{hr(10, 0)}}},
+	}
+	for _, tt := range tests {
+		if got, err := Coalesce(tt.wants, tt.opt); err == nil {
+			t.Errorf("%s: got %+v, want error", tt.name, got)
+		}
+	}
+}
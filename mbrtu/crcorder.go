@@ -0,0 +1,86 @@
+package mbrtu
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"ckklearn.com/testmodbus/global"
+	"ckklearn.com/testmodbus/mbrtu/request"
+)
+
+// 自动识别crc字节序的标记类型
+// 嵌入小端字节序（标准mbrtu）以满足`binary.ByteOrder`接口
+type autoCrcOrder struct {
+	binary.ByteOrder
+}
+
+func (autoCrcOrder) String() string {
+	return "AutoCrcOrder"
+}
+
+// AutoCrcOrder 自动识别crc字节序
+// 作为crcOrder传入时，主站按从站号学习并缓存从站使用的crc字节序
+var AutoCrcOrder binary.ByteOrder = autoCrcOrder{binary.LittleEndian}
+
+// 另一种crc字节序
+func swapCrcOrder(o binary.ByteOrder) binary.ByteOrder {
+	if o == binary.BigEndian {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}
+
+// 自动识别crc字节序并发送请求
+// 未识别过的从站先尝试标准的小端字节序，crc校验失败或超时则尝试另一种字节序
+// 已识别过的从站只在crc校验失败时尝试另一种字节序
+func (m *RtuMaster) autoCrcReadWrite(p []byte, r request.RtuRequest, addr byte, d *Dialect) (int, error) {
+	m.cl.RLock()
+	order, known := m.crcOrders[addr]
+	m.cl.RUnlock()
+	if !known {
+		order = binary.LittleEndian
+	}
+
+	n, err := m.baseReadWrite(p, r, addr, d, order)
+
+	// 广播和不返回报文的请求无法识别字节序
+	if addr == 0 || r.ExpectedLen() == 0 {
+		return n, err
+	}
+
+	var crcErr CrcError
+	retry := errors.As(err, &crcErr) || (!known && errors.Is(err, ErrReadTimeout))
+	if retry {
+		order = swapCrcOrder(order)
+		n, err = m.baseReadWrite(p, r, addr, d, order)
+	}
+
+	// 收到了从站的正常返回或异常返回，说明字节序正确
+	var slaveErr global.SlaveError
+	if err == nil || errors.As(err, &slaveErr) {
+		m.cl.Lock()
+		m.crcOrders[addr] = order
+		m.cl.Unlock()
+	}
+	return n, err
+}
+
+// CrcOrders 自动识别得到的从站crc字节序
+// 返回从站号到字节序的副本
+func (m *RtuMaster) CrcOrders() map[byte]binary.ByteOrder {
+	m.cl.RLock()
+	defer m.cl.RUnlock()
+	res := make(map[byte]binary.ByteOrder, len(m.crcOrders))
+	for addr, o := range m.crcOrders {
+		res[addr] = o
+	}
+	return res
+}
+
+// ForgetCrcOrder 清除从站已识别的crc字节序
+// 下次请求时重新识别
+func (m *RtuMaster) ForgetCrcOrder(addr byte) {
+	m.cl.Lock()
+	defer m.cl.Unlock()
+	delete(m.crcOrders, addr)
+}
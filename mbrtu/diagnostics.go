@@ -0,0 +1,136 @@
+package mbrtu
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"ckklearn.com/testmodbus/global"
+	"ckklearn.com/testmodbus/mbrtu/request"
+)
+
+// 发送诊断请求，校验从站回显的子功能码
+// 返回子功能码之后的数据
+func (m *RtuMaster) diagnostics(addr byte, sub global.DiagSubFunCode, data []uint16, crcOrder binary.ByteOrder) ([]uint16, error) {
+	p := make([]byte, 2+len(data)*2)
+	n, err := m.BaseReadWrite(p, request.NewRtuDiagnosticsRequest(addr, sub, data), crcOrder)
+	if err != nil {
+		return nil, err
+	}
+	// 强制只听模式下从站不返回报文
+	if sub == global.DiagForceListenOnlyMode {
+		return nil, nil
+	}
+	if n < 2 || n%2 != 0 {
+		return nil, fmt.Errorf("invalid diagnostics response length %d", n)
+	}
+
+	res := make([]uint16, n/2)
+	binary.Read(bytes.NewReader(p[:n]), binary.BigEndian, res)
+	if res[0] != sub {
+		return nil, fmt.Errorf("diagnostics echo mismatch: sub-function %x, echo %x", sub, res[0])
+	}
+	return res[1:], nil
+}
+
+// 发送诊断请求并校验数据回显
+func (m *RtuMaster) diagnosticsEcho(addr byte, sub global.DiagSubFunCode, data []uint16, crcOrder binary.ByteOrder) error {
+	res, err := m.diagnostics(addr, sub, data, crcOrder)
+	if err != nil {
+		return err
+	}
+	if len(res) != len(data) {
+		return fmt.Errorf("diagnostics echo mismatch: sent %d words, echo %d words", len(data), len(res))
+	}
+	for i := range data {
+		if res[i] != data[i] {
+			return fmt.Errorf("diagnostics echo mismatch at word %d: sent %x, echo %x", i, data[i], res[i])
+		}
+	}
+	return nil
+}
+
+// 发送诊断请求读取单个计数器或寄存器
+func (m *RtuMaster) diagnosticsValue(addr byte, sub global.DiagSubFunCode, crcOrder binary.ByteOrder) (uint16, error) {
+	res, err := m.diagnostics(addr, sub, []uint16{0}, crcOrder)
+	if err != nil {
+		return 0, err
+	}
+	if len(res) != 1 {
+		return 0, fmt.Errorf("invalid diagnostics response: expected 1 word, got %d", len(res))
+	}
+	return res[0], nil
+}
+
+// ReturnQueryData 回送诊断（环回测试）
+// 从站应原样返回发送的数据
+func (m *RtuMaster) ReturnQueryData(addr byte, data []uint16, crcOrder binary.ByteOrder) error {
+	return m.diagnosticsEcho(addr, global.DiagReturnQueryData, data, crcOrder)
+}
+
+// RestartCommunications 重启从站通信
+// 从站会退出只听模式，clearLog为true时同时清空通信事件日志
+func (m *RtuMaster) RestartCommunications(addr byte, clearLog bool, crcOrder binary.ByteOrder) error {
+	var data uint16
+	if clearLog {
+		data = 0xff00
+	}
+	return m.diagnosticsEcho(addr, global.DiagRestartCommunications, []uint16{data}, crcOrder)
+}
+
+// ReturnDiagnosticRegister 读取从站诊断寄存器
+func (m *RtuMaster) ReturnDiagnosticRegister(addr byte, crcOrder binary.ByteOrder) (uint16, error) {
+	return m.diagnosticsValue(addr, global.DiagReturnDiagnosticRegister, crcOrder)
+}
+
+// ForceListenOnlyMode 强制从站进入只听模式
+// 从站不返回报文，只能通过RestartCommunications恢复
+func (m *RtuMaster) ForceListenOnlyMode(addr byte, crcOrder binary.ByteOrder) error {
+	_, err := m.diagnostics(addr, global.DiagForceListenOnlyMode, []uint16{0}, crcOrder)
+	return err
+}
+
+// ClearCounters 清空从站所有计数器和诊断寄存器
+func (m *RtuMaster) ClearCounters(addr byte, crcOrder binary.ByteOrder) error {
+	return m.diagnosticsEcho(addr, global.DiagClearCounters, []uint16{0}, crcOrder)
+}
+
+// ReturnBusMessageCount 读取从站检测到的总线报文数
+func (m *RtuMaster) ReturnBusMessageCount(addr byte, crcOrder binary.ByteOrder) (uint16, error) {
+	return m.diagnosticsValue(addr, global.DiagReturnBusMessageCount, crcOrder)
+}
+
+// ReturnBusCommErrorCount 读取从站检测到的crc错误数
+func (m *RtuMaster) ReturnBusCommErrorCount(addr byte, crcOrder binary.ByteOrder) (uint16, error) {
+	return m.diagnosticsValue(addr, global.DiagReturnBusCommErrorCount, crcOrder)
+}
+
+// ReturnBusExceptionErrorCount 读取从站返回的异常报文数
+func (m *RtuMaster) ReturnBusExceptionErrorCount(addr byte, crcOrder binary.ByteOrder) (uint16, error) {
+	return m.diagnosticsValue(addr, global.DiagReturnBusExceptionErrorCount, crcOrder)
+}
+
+// ReturnSlaveMessageCount 读取从站处理的报文数
+func (m *RtuMaster) ReturnSlaveMessageCount(addr byte, crcOrder binary.ByteOrder) (uint16, error) {
+	return m.diagnosticsValue(addr, global.DiagReturnSlaveMessageCount, crcOrder)
+}
+
+// ReturnSlaveNoResponseCount 读取从站未返回报文的请求数
+func (m *RtuMaster) ReturnSlaveNoResponseCount(addr byte, crcOrder binary.ByteOrder) (uint16, error) {
+	return m.diagnosticsValue(addr, global.DiagReturnSlaveNoResponseCount, crcOrder)
+}
+
+// ReturnSlaveNAKCount 读取从站返回否定确认异常的次数
+func (m *RtuMaster) ReturnSlaveNAKCount(addr byte, crcOrder binary.ByteOrder) (uint16, error) {
+	return m.diagnosticsValue(addr, global.DiagReturnSlaveNAKCount, crcOrder)
+}
+
+// ReturnSlaveBusyCount 读取从站返回忙异常的次数
+func (m *RtuMaster) ReturnSlaveBusyCount(addr byte, crcOrder binary.ByteOrder) (uint16, error) {
+	return m.diagnosticsValue(addr, global.DiagReturnSlaveBusyCount, crcOrder)
+}
+
+// ReturnBusCharOverrunCount 读取从站字符溢出导致无法处理的报文数
+func (m *RtuMaster) ReturnBusCharOverrunCount(addr byte, crcOrder binary.ByteOrder) (uint16, error) {
+	return m.diagnosticsValue(addr, global.DiagReturnBusCharOverrunCount, crcOrder)
+}
@@ -0,0 +1,157 @@
+package mbrtu
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"ckklearn.com/testmodbus/global"
+	"ckklearn.com/testmodbus/mbrtu/request"
+)
+
+// Dialect 从站的协议变体
+// 描述从站与标准mbrtu的差异，影响请求的编码、期望的返回报文长度和返回报文的解析
+type Dialect struct {
+	Name          string                 // 变体名称
+	WideByteCount bool                   // 写多个寄存器请求的字节数字段占2个字节
+	WideReadCount bool                   // 读请求返回报文的字节数字段占2个字节，不与Enron模式同时使用
+	CrcOrder      binary.ByteOrder       // 强制使用的crc字节序，为nil时使用调用时传入的字节序
+	NoWriteEcho   bool                   // 写请求后从站不返回报文
+	NoMultiWrite  bool                   // 从站不支持写多个线圈和写多个寄存器，批量写入时逐个写入
+	OffsetBase    uint16                 // 从站地址的起始编号，请求中的偏移量会加上这个值
+	Enron         request.EnronRanges    // Enron模式下32位寄存器的地址范围，为nil时不使用Enron模式
+	Exceptions    *global.ExceptionTable // 变体的异常码表，为nil时使用默认的异常码表
+}
+
+// StandardDialect 标准mbrtu
+var StandardDialect = &Dialect{Name: "standard"}
+
+// NRDialect 南瑞项目的变体
+// 写多个寄存器请求的字节数字段占2个字节
+var NRDialect = &Dialect{Name: "nr", WideByteCount: true}
+
+var (
+	dialects = map[string]*Dialect{
+		StandardDialect.Name: StandardDialect,
+		NRDialect.Name:       NRDialect,
+	}
+	dialectLock = new(sync.RWMutex)
+)
+
+// RegisterDialect 按名称注册协议变体
+// 重复注册会覆盖同名的变体
+func RegisterDialect(d *Dialect) error {
+	if d == nil || d.Name == "" {
+		return fmt.Errorf("dialect requires a name")
+	}
+	dialectLock.Lock()
+	defer dialectLock.Unlock()
+	dialects[d.Name] = d
+	return nil
+}
+
+// LookupDialect 按名称查找协议变体
+func LookupDialect(name string) (*Dialect, bool) {
+	dialectLock.RLock()
+	defer dialectLock.RUnlock()
+	d, ok := dialects[name]
+	return d, ok
+}
+
+// 请求实际使用的crc字节序
+func (d *Dialect) crcOrder(o binary.ByteOrder) binary.ByteOrder {
+	if d.CrcOrder != nil {
+		return d.CrcOrder
+	}
+	return o
+}
+
+// 请求报文中实际使用的偏移量
+func (d *Dialect) offset(o uint16) uint16 {
+	return o + d.OffsetBase
+}
+
+// 单次读取数据区的最大个数
+// 字节数字段占2个字节时不受标准mbrtu报文长度的限制
+func (d *Dialect) maxReadNum(area global.Area) int {
+	if d.WideReadCount {
+		return 0xffff
+	}
+	return int(area.MaxReadNum())
+}
+
+// 构造读请求
+// Enron模式下读取的寄存器全部位于同一个32位寄存器地址范围内时使用对应的请求
+func (d *Dialect) readRequest(addr byte, fun global.FunCode, offset, num uint16) request.RtuRequest {
+	offset = d.offset(offset)
+	if d.WideReadCount {
+		return request.NewRtuWideReadRequest(addr, fun, offset, num)
+	}
+	switch fun {
+	case global.ReadHoldingRegisters, global.ReadInputRegisters:
+		if d.Enron.ContainsRange(offset, num) {
+			return request.NewRtuEnronReadRequest(addr, fun, offset, num)
+		}
+	}
+	return request.NewRtuReadRequest(addr, fun, offset, num)
+}
+
+// 包装写请求
+// 从站不回显时不等待返回报文
+func (d *Dialect) writeRequest(r request.RtuRequest) request.RtuRequest {
+	if d.NoWriteEcho {
+		return request.NewRtuNoEchoRequest(r)
+	}
+	return r
+}
+
+// 构造写单个请求
+func (d *Dialect) writeSingleRequest(addr byte, fun global.FunCode, offset, data uint16) request.RtuRequest {
+	return d.writeRequest(request.NewRtuWriteSingleRequest(addr, fun, d.offset(offset), data))
+}
+
+// 构造写多个线圈请求
+func (d *Dialect) writeMultiCoilsRequest(addr byte, offset, num uint16, data []byte) request.RtuRequest {
+	return d.writeRequest(request.NewRtuWriteMultiCoilsRequest(addr, d.offset(offset), num, data))
+}
+
+// 构造写多个寄存器请求
+func (d *Dialect) writeMultiRegsRequest(addr byte, offset uint16, data []uint16) request.RtuRequest {
+	if d.WideByteCount {
+		return d.writeRequest(request.NewRtuWideWriteMultiRegsRequest(addr, d.offset(offset), data))
+	}
+	return d.writeRequest(request.NewRtuWriteMultiRegsRequest(addr, d.offset(offset), data))
+}
+
+// SetDialect 配置默认的协议变体
+// 未单独配置协议变体的从站使用这个变体，`d` 为nil时恢复为标准mbrtu
+func (m *RtuMaster) SetDialect(d *Dialect) {
+	if d == nil {
+		d = StandardDialect
+	}
+	m.cl.Lock()
+	defer m.cl.Unlock()
+	m.dialect = d
+}
+
+// SetSlaveDialect 为从站配置协议变体
+// `d` 为nil时恢复使用默认的协议变体
+func (m *RtuMaster) SetSlaveDialect(addr byte, d *Dialect) {
+	m.cl.Lock()
+	defer m.cl.Unlock()
+	if d == nil {
+		delete(m.dialects, addr)
+		return
+	}
+	m.dialects[addr] = d
+}
+
+// 获取从站的协议变体
+func (m *RtuMaster) dialectFor(addr byte) *Dialect {
+	m.cl.RLock()
+	defer m.cl.RUnlock()
+	if d, ok := m.dialects[addr]; ok {
+		return d
+	}
+	return m.dialect
+}
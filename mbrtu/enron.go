@@ -0,0 +1,59 @@
+package mbrtu
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"ckklearn.com/testmodbus/mbrtu/request"
+)
+
+// NewEnronDialect 构造Enron模式的协议变体
+// 读取`ranges`范围内的寄存器时按每个寄存器4个字节计算返回报文长度
+func NewEnronDialect(name string, ranges request.EnronRanges) *Dialect {
+	return &Dialect{Name: name, Enron: ranges}
+}
+
+// 读取Enron模式下的32位保持寄存器
+func (m *RtuMaster) readEnron(addr byte, offset, num uint16, crcOrder binary.ByteOrder) ([]uint32, error) {
+	if num == 0 || num > request.MaxEnronReadNum {
+		return nil, fmt.Errorf("enron read num must be 1~%d", request.MaxEnronReadNum)
+	}
+	d := m.dialectFor(addr)
+	if !d.Enron.ContainsRange(d.offset(offset), num) {
+		return nil, fmt.Errorf("registers %d~%d are not in one enron 32-bit range", offset, int(offset)+int(num)-1)
+	}
+
+	p := make([]byte, int(num)*4)
+	n, err := m.ReadHoldingRegisters(p, addr, offset, num, crcOrder)
+	if err != nil {
+		return nil, err
+	}
+	if n != len(p) {
+		return nil, fmt.Errorf("invalid enron response length %d, expected %d", n, len(p))
+	}
+
+	res := make([]uint32, num)
+	for i := range res {
+		res[i] = binary.BigEndian.Uint32(p[i*4:])
+	}
+	return res, nil
+}
+
+// ReadEnronUint32s 读取Enron模式下的32位整数寄存器
+func (m *RtuMaster) ReadEnronUint32s(addr byte, offset, num uint16, crcOrder binary.ByteOrder) ([]uint32, error) {
+	return m.readEnron(addr, offset, num, crcOrder)
+}
+
+// ReadEnronFloat32s 读取Enron模式下的32位浮点数寄存器
+func (m *RtuMaster) ReadEnronFloat32s(addr byte, offset, num uint16, crcOrder binary.ByteOrder) ([]float32, error) {
+	raw, err := m.readEnron(addr, offset, num, crcOrder)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]float32, len(raw))
+	for i, v := range raw {
+		res[i] = math.Float32frombits(v)
+	}
+	return res, nil
+}
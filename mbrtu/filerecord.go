@@ -0,0 +1,149 @@
+package mbrtu
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"ckklearn.com/testmodbus/mbrtu/request"
+)
+
+const (
+	// 单个读文件记录请求最多读取的记录数
+	// 返回数据不超过245字节：2字节子返回头加上每个记录2字节
+	maxReadFileRecords int = 121
+	// 单个写文件记录请求最多写入的记录数
+	// 请求数据不超过245字节：7字节子请求头加上每个记录2字节
+	maxWriteFileRecords int = 119
+)
+
+// FileProgress 文件传输进度回调
+// `done` 为已传输的字节数，`total` 为总字节数
+type FileProgress func(done, total int)
+
+// ReadFileRecord 读文件记录
+// 按子请求的顺序返回读取到的记录，`Data` 中为读取的数据
+func (m *RtuMaster) ReadFileRecord(addr byte, records []request.FileRecord, crcOrder binary.ByteOrder) ([]request.FileRecord, error) {
+	r := request.NewRtuReadFileRecordRequest(addr, records)
+	p := make([]byte, r.ExpectedLen())
+	n, err := m.BaseReadWrite(p, r, crcOrder)
+	if err != nil {
+		return nil, err
+	}
+
+	// 逐个解析子返回：1字节长度、1字节引用类型、记录数据
+	res := make([]request.FileRecord, len(records))
+	idx := 0
+	for i, fr := range records {
+		if idx+2 > n {
+			return nil, fmt.Errorf("file record response truncated at sub-request %d", i)
+		}
+		subLen := int(p[idx])
+		if subLen != int(fr.Length)*2+1 || idx+1+subLen > n {
+			return nil, fmt.Errorf("invalid file record response length %d at sub-request %d", subLen, i)
+		}
+		if p[idx+1] != request.FileRefType {
+			return nil, fmt.Errorf("invalid file record reference type %d at sub-request %d", p[idx+1], i)
+		}
+		data := make([]uint16, fr.Length)
+		binary.Read(bytes.NewReader(p[idx+2:idx+1+subLen]), binary.BigEndian, data)
+		res[i] = request.FileRecord{
+			File:   fr.File,
+			Record: fr.Record,
+			Length: fr.Length,
+			Data:   data,
+		}
+		idx += 1 + subLen
+	}
+	return res, nil
+}
+
+// WriteFileRecord 写文件记录
+// 校验从站回显的数据与请求一致
+func (m *RtuMaster) WriteFileRecord(addr byte, records []request.FileRecord, crcOrder binary.ByteOrder) error {
+	r := request.NewRtuWriteFileRecordRequest(addr, records)
+	payload, err := r.Payload()
+	if err != nil {
+		return err
+	}
+	p := make([]byte, len(payload))
+	n, err := m.BaseReadWrite(p, r, crcOrder)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(p[:n], payload) {
+		return fmt.Errorf("write file record echo mismatch")
+	}
+	return nil
+}
+
+// ReadFile 从文件中读取指定字节长度的数据
+// 从`record`号记录开始，按请求大小限制拆分为多次读取
+func (m *RtuMaster) ReadFile(addr byte, file, record uint16, size int, crcOrder binary.ByteOrder, progress FileProgress) ([]byte, error) {
+	if size < 0 {
+		return nil, fmt.Errorf("invalid file size %d", size)
+	}
+	// 每个记录为2个字节，奇数长度时多读一个记录
+	total := (size + 1) / 2
+	buf := bytes.NewBuffer(make([]byte, 0, total*2))
+	for done := 0; done < total; {
+		num := total - done
+		if num > maxReadFileRecords {
+			num = maxReadFileRecords
+		}
+		res, err := m.ReadFileRecord(addr, []request.FileRecord{{
+			File:   file,
+			Record: record + uint16(done),
+			Length: uint16(num),
+		}}, crcOrder)
+		if err != nil {
+			return nil, err
+		}
+		binary.Write(buf, binary.BigEndian, res[0].Data)
+		done += num
+
+		if progress != nil {
+			n := done * 2
+			if n > size {
+				n = size
+			}
+			progress(n, size)
+		}
+	}
+	return buf.Bytes()[:size], nil
+}
+
+// WriteFile 将数据写入文件
+// 从`record`号记录开始，按请求大小限制拆分为多次写入，奇数长度时末尾补0
+func (m *RtuMaster) WriteFile(addr byte, file, record uint16, data []byte, crcOrder binary.ByteOrder, progress FileProgress) error {
+	size := len(data)
+	regs := make([]uint16, (size+1)/2)
+	for i, b := range data {
+		regs[i/2] |= uint16(b) << (8 * uint(1-i%2))
+	}
+
+	for done := 0; done < len(regs); {
+		num := len(regs) - done
+		if num > maxWriteFileRecords {
+			num = maxWriteFileRecords
+		}
+		err := m.WriteFileRecord(addr, []request.FileRecord{{
+			File:   file,
+			Record: record + uint16(done),
+			Data:   regs[done : done+num],
+		}}, crcOrder)
+		if err != nil {
+			return err
+		}
+		done += num
+
+		if progress != nil {
+			n := done * 2
+			if n > size {
+				n = size
+			}
+			progress(n, size)
+		}
+	}
+	return nil
+}
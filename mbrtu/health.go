@@ -0,0 +1,118 @@
+package mbrtu
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"ckklearn.com/testmodbus/global"
+)
+
+// ErrSlaveOffline 从站已离线
+// 离线的从站在探测间隔内的请求直接返回这个错误，不占用总线
+var ErrSlaveOffline = errors.New("slave offline")
+
+// HealthConfig 从站健康跟踪的参数
+type HealthConfig struct {
+	Threshold     int           // 连续通信失败多少次后认为从站离线，为0时不标记离线
+	ProbeInterval time.Duration // 离线从站的探测间隔，每个间隔只放行一个请求
+}
+
+// SlaveHealth 从站的健康状态
+type SlaveHealth struct {
+	Online       bool
+	Failures     int       // 连续通信失败的次数
+	LastError    error     // 最近一次通信失败的错误
+	LastSuccess  time.Time // 最近一次通信成功的时间
+	LastFailure  time.Time // 最近一次通信失败的时间
+	OfflineSince time.Time // 离线的时间
+	NextProbe    time.Time // 离线时下次允许探测的时间
+}
+
+// SetHealthConfig 配置从站健康跟踪的参数
+// 默认不标记离线，只统计通信失败
+func (m *RtuMaster) SetHealthConfig(c HealthConfig) {
+	m.cl.Lock()
+	defer m.cl.Unlock()
+	m.healthConf = c
+}
+
+// SlaveHealth 从站的健康状态
+// 从未通信过的从站返回false
+func (m *RtuMaster) SlaveHealth(addr byte) (SlaveHealth, bool) {
+	m.cl.RLock()
+	defer m.cl.RUnlock()
+	h, ok := m.health[addr]
+	if !ok {
+		return SlaveHealth{}, false
+	}
+	return *h, true
+}
+
+// SlaveHealths 所有通信过的从站的健康状态
+// 返回从站号到状态的副本
+func (m *RtuMaster) SlaveHealths() map[byte]SlaveHealth {
+	m.cl.RLock()
+	defer m.cl.RUnlock()
+	res := make(map[byte]SlaveHealth, len(m.health))
+	for addr, h := range m.health {
+		res[addr] = *h
+	}
+	return res
+}
+
+// ResetSlaveHealth 清除从站的健康状态，离线的从站恢复为在线
+func (m *RtuMaster) ResetSlaveHealth(addr byte) {
+	m.cl.Lock()
+	defer m.cl.Unlock()
+	delete(m.health, addr)
+}
+
+// 检查从站是否可以通信
+// 离线的从站到了探测时间时放行本次请求，并推迟下次探测的时间
+func (m *RtuMaster) checkHealth(addr byte) error {
+	m.cl.Lock()
+	defer m.cl.Unlock()
+	h, ok := m.health[addr]
+	if !ok || h.Online {
+		return nil
+	}
+	now := time.Now()
+	if now.Before(h.NextProbe) {
+		return fmt.Errorf("slave %d: %w", addr, ErrSlaveOffline)
+	}
+	h.NextProbe = now.Add(m.healthConf.ProbeInterval)
+	return nil
+}
+
+// 按请求的结果更新从站的健康状态
+// 正常返回和异常返回都说明从站在线，超时和crc校验失败记为通信失败，其它错误不影响健康状态
+func (m *RtuMaster) updateHealth(addr byte, err error) {
+	var slaveErr global.SlaveError
+	var crcErr CrcError
+	success := err == nil || errors.As(err, &slaveErr)
+	if !success && !errors.Is(err, ErrReadTimeout) && !errors.As(err, &crcErr) {
+		return
+	}
+
+	m.cl.Lock()
+	defer m.cl.Unlock()
+	h, ok := m.health[addr]
+	if !ok {
+		h = &SlaveHealth{Online: true}
+		m.health[addr] = h
+	}
+	now := time.Now()
+	if success {
+		*h = SlaveHealth{Online: true, LastSuccess: now, LastFailure: h.LastFailure, LastError: h.LastError}
+		return
+	}
+
+	h.Failures++
+	h.LastError, h.LastFailure = err, now
+	if h.Online && m.healthConf.Threshold > 0 && h.Failures >= m.healthConf.Threshold {
+		h.Online = false
+		h.OfflineSince = now
+		h.NextProbe = now.Add(m.healthConf.ProbeInterval)
+	}
+}
@@ -0,0 +1,105 @@
+package mbrtu
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"ckklearn.com/testmodbus/global"
+)
+
+func newHealthMaster(c HealthConfig) *RtuMaster {
+	return &RtuMaster{
+		cl:         new(sync.RWMutex),
+		health:     make(map[byte]*SlaveHealth),
+		healthConf: c,
+	}
+}
+
+func TestHealthOffline(t *testing.T) {
+	m := newHealthMaster(HealthConfig{Threshold: 3, ProbeInterval: time.Hour})
+	timeout := fmt.Errorf("read: %w", ErrReadTimeout)
+
+	for i := 1; i <= 3; i++ {
+		if err := m.checkHealth(1); err != nil {
+			t.Fatalf("request %d before threshold: %v", i, err)
+		}
+		m.updateHealth(1, timeout)
+		h, _ := m.SlaveHealth(1)
+		if h.Failures != i || h.Online != (i < 3) {
+			t.Errorf("after %d failures: failures %d online %t", i, h.Failures, h.Online)
+		}
+	}
+	// 探测时间未到时直接返回离线
+	if err := m.checkHealth(1); !errors.Is(err, ErrSlaveOffline) {
+		t.Errorf("offline check = %v, want ErrSlaveOffline", err)
+	}
+	// 其它从站不受影响
+	if err := m.checkHealth(2); err != nil {
+		t.Errorf("other slave check = %v", err)
+	}
+
+	// 每个探测间隔只放行一个请求
+	m.health[1].NextProbe = time.Now().Add(-time.Millisecond)
+	if err := m.checkHealth(1); err != nil {
+		t.Errorf("probe check = %v, want nil", err)
+	}
+	if err := m.checkHealth(1); !errors.Is(err, ErrSlaveOffline) {
+		t.Errorf("second probe check = %v, want ErrSlaveOffline", err)
+	}
+	if h, _ := m.SlaveHealth(1); time.Until(h.NextProbe) < 59*time.Minute {
+		t.Errorf("next probe in %s, want about 1h", time.Until(h.NextProbe))
+	}
+
+	// 探测失败仍然离线
+	m.updateHealth(1, CrcError{ReadCrc: 1, CalCrc: 2})
+	if h, _ := m.SlaveHealth(1); h.Online || h.Failures != 4 {
+		t.Errorf("after failed probe: online %t failures %d", h.Online, h.Failures)
+	}
+}
+
+func TestHealthRecover(t *testing.T) {
+	results := map[string]error{
+		"success":   nil,
+		"exception": global.DefaultExceptionTable.Lookup(0x02),
+	}
+	for name, res := range results {
+		m := newHealthMaster(HealthConfig{Threshold: 1, ProbeInterval: time.Hour})
+		m.updateHealth(1, ErrReadTimeout)
+		if h, _ := m.SlaveHealth(1); h.Online {
+			t.Fatalf("%s: slave online after threshold", name)
+		}
+		m.updateHealth(1, res)
+		h, _ := m.SlaveHealth(1)
+		if !h.Online || h.Failures != 0 || h.LastSuccess.IsZero() || h.LastError != ErrReadTimeout {
+			t.Errorf("%s: recovered health = %+v", name, h)
+		}
+		if err := m.checkHealth(1); err != nil {
+			t.Errorf("%s: check after recovery = %v", name, err)
+		}
+	}
+}
+
+func TestHealthIgnoredErrors(t *testing.T) {
+	m := newHealthMaster(HealthConfig{Threshold: 1})
+	m.updateHealth(1, errors.New("invalid response length"))
+	if _, ok := m.SlaveHealth(1); ok {
+		t.Error("non-transport error recorded")
+	}
+
+	// 阈值为0时只统计失败，不标记离线
+	m = newHealthMaster(HealthConfig{})
+	for i := 0; i < 5; i++ {
+		m.updateHealth(1, ErrReadTimeout)
+	}
+	if h, _ := m.SlaveHealth(1); !h.Online || h.Failures != 5 {
+		t.Errorf("without threshold: online %t failures %d", h.Online, h.Failures)
+	}
+
+	m.ResetSlaveHealth(1)
+	if _, ok := m.SlaveHealth(1); ok {
+		t.Error("health kept after reset")
+	}
+}
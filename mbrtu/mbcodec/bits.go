@@ -0,0 +1,31 @@
+package mbcodec
+
+import (
+	"fmt"
+)
+
+// Bools 将线圈或离散输入的字节解码为布尔值
+// 按字节内低位在前解包，丢弃最后一个字节的填充位
+// 字节数必须等于`num`除以8向上取整
+func Bools(b []byte, num int) ([]bool, error) {
+	if len(b) != (num+7)/8 {
+		return nil, fmt.Errorf("invalid byte count %d for %d bits", len(b), num)
+	}
+	res := make([]bool, num)
+	for i := range res {
+		res[i] = b[i/8]&(1<<uint(i%8)) != 0
+	}
+	return res, nil
+}
+
+// EncodeBools 将布尔值编码为线圈字节
+// 按字节内低位在前打包，最后一个字节不足的位补0
+func EncodeBools(v []bool) []byte {
+	b := make([]byte, (len(v)+7)/8)
+	for i, on := range v {
+		if on {
+			b[i/8] |= 1 << uint(i%8)
+		}
+	}
+	return b
+}
@@ -0,0 +1,52 @@
+package mbcodec
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBools(t *testing.T) {
+	got, err := Bools([]byte{0xcd, 0x01}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []bool{true, false, true, true, false, false, true, true, true, false}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Bools = %v, want %v", got, want)
+	}
+
+	// 字节数必须与位数一致
+	for _, tt := range []struct {
+		b   []byte
+		num int
+	}{
+		{[]byte{0xff}, 9},
+		{[]byte{0xff, 0x01}, 8},
+		{nil, 1},
+	} {
+		if _, err := Bools(tt.b, tt.num); err == nil {
+			t.Errorf("Bools(%x, %d), want error", tt.b, tt.num)
+		}
+	}
+}
+
+func TestEncodeBools(t *testing.T) {
+	v := []bool{true, false, true, true, false, false, true, true, true, false}
+	if got := EncodeBools(v); !reflect.DeepEqual(got, []byte{0xcd, 0x01}) {
+		t.Errorf("EncodeBools = %x, want cd01", got)
+	}
+	if got := EncodeBools(nil); len(got) != 0 {
+		t.Errorf("EncodeBools(nil) = %x, want empty", got)
+	}
+
+	for num := 1; num <= 17; num++ {
+		v := make([]bool, num)
+		for i := range v {
+			v[i] = i%3 == 0
+		}
+		got, err := Bools(EncodeBools(v), num)
+		if err != nil || !reflect.DeepEqual(got, v) {
+			t.Errorf("%d bits round trip = %v, %v", num, got, err)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+// Package mbcodec 寄存器数据与数值之间的编解码
+package mbcodec
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Registers 将大端字节转换为寄存器
+// 奇数长度时末尾补0
+func Registers(b []byte) []uint16 {
+	regs := make([]uint16, (len(b)+1)/2)
+	for i, v := range b {
+		regs[i/2] |= uint16(v) << (8 * uint(1-i%2))
+	}
+	return regs
+}
+
+// Bytes 将寄存器转换为大端字节
+func Bytes(regs []uint16) []byte {
+	b := make([]byte, len(regs)*2)
+	for i, r := range regs {
+		binary.BigEndian.PutUint16(b[i*2:], r)
+	}
+	return b
+}
+
+// 将寄存器字节按数值拆分，转换为大端排列
+func decode(b []byte, size int, o Order) ([][]byte, error) {
+	if len(b)%size != 0 {
+		return nil, fmt.Errorf("data length %d is not a multiple of %d", len(b), size)
+	}
+	vals := make([][]byte, len(b)/size)
+	for i := range vals {
+		vals[i] = make([]byte, size)
+		o.arrange(vals[i], b[i*size:(i+1)*size])
+	}
+	return vals, nil
+}
+
+// 将大端排列的数值转换为寄存器
+func encode(vals [][]byte, o Order) []uint16 {
+	if len(vals) == 0 {
+		return []uint16{}
+	}
+	size := len(vals[0])
+	b := make([]byte, len(vals)*size)
+	for i, v := range vals {
+		o.arrange(b[i*size:], v)
+	}
+	return Registers(b)
+}
+
+// ---- 解码 ----
+
+// Uint16s 解码为uint16
+// 只有寄存器内字节交换的排列顺序有影响
+func Uint16s(b []byte, o Order) ([]uint16, error) {
+	vals, err := decode(b, 2, o)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]uint16, len(vals))
+	for i, v := range vals {
+		res[i] = binary.BigEndian.Uint16(v)
+	}
+	return res, nil
+}
+
+// Int16s 解码为int16
+func Int16s(b []byte, o Order) ([]int16, error) {
+	vals, err := Uint16s(b, o)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]int16, len(vals))
+	for i, v := range vals {
+		res[i] = int16(v)
+	}
+	return res, nil
+}
+
+// Uint32s 解码为uint32
+func Uint32s(b []byte, o Order) ([]uint32, error) {
+	vals, err := decode(b, 4, o)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]uint32, len(vals))
+	for i, v := range vals {
+		res[i] = binary.BigEndian.Uint32(v)
+	}
+	return res, nil
+}
+
+// Int32s 解码为int32
+func Int32s(b []byte, o Order) ([]int32, error) {
+	vals, err := Uint32s(b, o)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]int32, len(vals))
+	for i, v := range vals {
+		res[i] = int32(v)
+	}
+	return res, nil
+}
+
+// Float32s 解码为float32
+func Float32s(b []byte, o Order) ([]float32, error) {
+	vals, err := Uint32s(b, o)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]float32, len(vals))
+	for i, v := range vals {
+		res[i] = math.Float32frombits(v)
+	}
+	return res, nil
+}
+
+// Uint64s 解码为uint64
+func Uint64s(b []byte, o Order) ([]uint64, error) {
+	vals, err := decode(b, 8, o)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]uint64, len(vals))
+	for i, v := range vals {
+		res[i] = binary.BigEndian.Uint64(v)
+	}
+	return res, nil
+}
+
+// Int64s 解码为int64
+func Int64s(b []byte, o Order) ([]int64, error) {
+	vals, err := Uint64s(b, o)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]int64, len(vals))
+	for i, v := range vals {
+		res[i] = int64(v)
+	}
+	return res, nil
+}
+
+// Float64s 解码为float64
+func Float64s(b []byte, o Order) ([]float64, error) {
+	vals, err := Uint64s(b, o)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]float64, len(vals))
+	for i, v := range vals {
+		res[i] = math.Float64frombits(v)
+	}
+	return res, nil
+}
+
+// ---- 编码 ----
+
+// EncodeUint16s 将uint16编码为寄存器
+func EncodeUint16s(v []uint16, o Order) []uint16 {
+	vals := make([][]byte, len(v))
+	for i, x := range v {
+		vals[i] = make([]byte, 2)
+		binary.BigEndian.PutUint16(vals[i], x)
+	}
+	return encode(vals, o)
+}
+
+// EncodeInt16s 将int16编码为寄存器
+func EncodeInt16s(v []int16, o Order) []uint16 {
+	u := make([]uint16, len(v))
+	for i, x := range v {
+		u[i] = uint16(x)
+	}
+	return EncodeUint16s(u, o)
+}
+
+// EncodeUint32s 将uint32编码为寄存器
+func EncodeUint32s(v []uint32, o Order) []uint16 {
+	vals := make([][]byte, len(v))
+	for i, x := range v {
+		vals[i] = make([]byte, 4)
+		binary.BigEndian.PutUint32(vals[i], x)
+	}
+	return encode(vals, o)
+}
+
+// EncodeInt32s 将int32编码为寄存器
+func EncodeInt32s(v []int32, o Order) []uint16 {
+	u := make([]uint32, len(v))
+	for i, x := range v {
+		u[i] = uint32(x)
+	}
+	return EncodeUint32s(u, o)
+}
+
+// EncodeFloat32s 将float32编码为寄存器
+func EncodeFloat32s(v []float32, o Order) []uint16 {
+	u := make([]uint32, len(v))
+	for i, x := range v {
+		u[i] = math.Float32bits(x)
+	}
+	return EncodeUint32s(u, o)
+}
+
+// EncodeUint64s 将uint64编码为寄存器
+func EncodeUint64s(v []uint64, o Order) []uint16 {
+	vals := make([][]byte, len(v))
+	for i, x := range v {
+		vals[i] = make([]byte, 8)
+		binary.BigEndian.PutUint64(vals[i], x)
+	}
+	return encode(vals, o)
+}
+
+// EncodeInt64s 将int64编码为寄存器
+func EncodeInt64s(v []int64, o Order) []uint16 {
+	u := make([]uint64, len(v))
+	for i, x := range v {
+		u[i] = uint64(x)
+	}
+	return EncodeUint64s(u, o)
+}
+
+// EncodeFloat64s 将float64编码为寄存器
+func EncodeFloat64s(v []float64, o Order) []uint16 {
+	u := make([]uint64, len(v))
+	for i, x := range v {
+		u[i] = math.Float64bits(x)
+	}
+	return EncodeUint64s(u, o)
+}
@@ -0,0 +1,121 @@
+package mbcodec
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+var orders = []Order{ABCD, CDAB, BADC, DCBA}
+
+func TestEncodeOrder(t *testing.T) {
+	tests := []struct {
+		o     Order
+		u16   []uint16
+		u32   []uint16
+		u64   []uint16
+		float []uint16
+	}{
+		{ABCD, []uint16{0x1122}, []uint16{0x1122, 0x3344}, []uint16{0x1122, 0x3344, 0x5566, 0x7788}, []uint16{0x3f80, 0x0000}},
+		{CDAB, []uint16{0x1122}, []uint16{0x3344, 0x1122}, []uint16{0x7788, 0x5566, 0x3344, 0x1122}, []uint16{0x0000, 0x3f80}},
+		{BADC, []uint16{0x2211}, []uint16{0x2211, 0x4433}, []uint16{0x2211, 0x4433, 0x6655, 0x8877}, []uint16{0x803f, 0x0000}},
+		{DCBA, []uint16{0x2211}, []uint16{0x4433, 0x2211}, []uint16{0x8877, 0x6655, 0x4433, 0x2211}, []uint16{0x0000, 0x803f}},
+	}
+	for _, tt := range tests {
+		if got := EncodeUint16s([]uint16{0x1122}, tt.o); !reflect.DeepEqual(got, tt.u16) {
+			t.Errorf("%s: EncodeUint16s = %04x, want %04x", tt.o, got, tt.u16)
+		}
+		if got := EncodeUint32s([]uint32{0x11223344}, tt.o); !reflect.DeepEqual(got, tt.u32) {
+			t.Errorf("%s: EncodeUint32s = %04x, want %04x", tt.o, got, tt.u32)
+		}
+		if got := EncodeUint64s([]uint64{0x1122334455667788}, tt.o); !reflect.DeepEqual(got, tt.u64) {
+			t.Errorf("%s: EncodeUint64s = %04x, want %04x", tt.o, got, tt.u64)
+		}
+		if got := EncodeFloat32s([]float32{1}, tt.o); !reflect.DeepEqual(got, tt.float) {
+			t.Errorf("%s: EncodeFloat32s = %04x, want %04x", tt.o, got, tt.float)
+		}
+
+		v, err := Uint32s(Bytes(tt.u32), tt.o)
+		if err != nil || v[0] != 0x11223344 {
+			t.Errorf("%s: Uint32s = %x, %v, want 11223344", tt.o, v, err)
+		}
+		f, err := Float32s(Bytes(tt.float), tt.o)
+		if err != nil || f[0] != 1 {
+			t.Errorf("%s: Float32s = %v, %v, want 1", tt.o, f, err)
+		}
+	}
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	for _, o := range orders {
+		i16 := []int16{0, 1, -1, math.MinInt16, math.MaxInt16}
+		if got, err := Int16s(Bytes(EncodeInt16s(i16, o)), o); err != nil || !reflect.DeepEqual(got, i16) {
+			t.Errorf("%s: int16 round trip = %v, %v", o, got, err)
+		}
+		u32 := []uint32{0, 1, 0xdeadbeef, math.MaxUint32}
+		if got, err := Uint32s(Bytes(EncodeUint32s(u32, o)), o); err != nil || !reflect.DeepEqual(got, u32) {
+			t.Errorf("%s: uint32 round trip = %v, %v", o, got, err)
+		}
+		i32 := []int32{0, -1, math.MinInt32, math.MaxInt32}
+		if got, err := Int32s(Bytes(EncodeInt32s(i32, o)), o); err != nil || !reflect.DeepEqual(got, i32) {
+			t.Errorf("%s: int32 round trip = %v, %v", o, got, err)
+		}
+		f32 := []float32{0, -1.5, 3.1415926, math.MaxFloat32}
+		if got, err := Float32s(Bytes(EncodeFloat32s(f32, o)), o); err != nil || !reflect.DeepEqual(got, f32) {
+			t.Errorf("%s: float32 round trip = %v, %v", o, got, err)
+		}
+		u64 := []uint64{0, 1, 0x0123456789abcdef, math.MaxUint64}
+		if got, err := Uint64s(Bytes(EncodeUint64s(u64, o)), o); err != nil || !reflect.DeepEqual(got, u64) {
+			t.Errorf("%s: uint64 round trip = %v, %v", o, got, err)
+		}
+		i64 := []int64{0, -1, math.MinInt64, math.MaxInt64}
+		if got, err := Int64s(Bytes(EncodeInt64s(i64, o)), o); err != nil || !reflect.DeepEqual(got, i64) {
+			t.Errorf("%s: int64 round trip = %v, %v", o, got, err)
+		}
+		f64 := []float64{0, -1.5, math.Pi, math.SmallestNonzeroFloat64}
+		if got, err := Float64s(Bytes(EncodeFloat64s(f64, o)), o); err != nil || !reflect.DeepEqual(got, f64) {
+			t.Errorf("%s: float64 round trip = %v, %v", o, got, err)
+		}
+	}
+}
+
+func TestDecodeLength(t *testing.T) {
+	if _, err := Uint16s([]byte{1, 2, 3}, ABCD); err == nil {
+		t.Error("Uint16s with 3 bytes, want error")
+	}
+	if _, err := Float32s(make([]byte, 6), ABCD); err == nil {
+		t.Error("Float32s with 6 bytes, want error")
+	}
+	if _, err := Uint64s(make([]byte, 4), ABCD); err == nil {
+		t.Error("Uint64s with 4 bytes, want error")
+	}
+}
+
+func TestRegistersBytes(t *testing.T) {
+	if got := Registers([]byte{0x12, 0x34, 0x56}); !reflect.DeepEqual(got, []uint16{0x1234, 0x5600}) {
+		t.Errorf("Registers = %04x", got)
+	}
+	if got := Bytes([]uint16{0x1234, 0x5678}); !reflect.DeepEqual(got, []byte{0x12, 0x34, 0x56, 0x78}) {
+		t.Errorf("Bytes = %x", got)
+	}
+}
+
+func TestParseOrder(t *testing.T) {
+	tests := map[string]Order{
+		"abcd":     ABCD,
+		"CDAB":     CDAB,
+		"badc":     BADC,
+		"DcBa":     DCBA,
+		"ghefcdab": CDAB,
+		"hgfedcba": DCBA,
+	}
+	for in, want := range tests {
+		got, err := ParseOrder(in)
+		if err != nil || got != want {
+			t.Errorf("ParseOrder(%q) = %s, %v, want %s", in, got, err, want)
+		}
+	}
+	if _, err := ParseOrder("acbd"); err == nil {
+		t.Error("ParseOrder(acbd), want error")
+	}
+}
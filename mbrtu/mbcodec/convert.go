@@ -0,0 +1,148 @@
+package mbcodec
+
+import (
+	"fmt"
+	"math"
+)
+
+// BCDToUint 将BCD编码的数值转换为整数
+// 任意一位超过9时返回错误
+func BCDToUint(v uint64) (uint64, error) {
+	var res, base uint64 = 0, 1
+	for raw := v; raw != 0; raw >>= 4 {
+		d := raw & 0x0f
+		if d > 9 {
+			return 0, fmt.Errorf("invalid bcd value %x", v)
+		}
+		res += d * base
+		base *= 10
+	}
+	return res, nil
+}
+
+// UintToBCD 将整数转换为BCD编码
+// 超过`digits`位时返回错误
+func UintToBCD(v uint64, digits int) (uint64, error) {
+	var res uint64
+	for i := 0; i < digits; i++ {
+		res |= (v % 10) << (4 * uint(i))
+		v /= 10
+	}
+	if v != 0 {
+		return 0, fmt.Errorf("value exceeds %d bcd digits", digits)
+	}
+	return res, nil
+}
+
+// Converter 对寄存器数值进行工程转换
+// 依次判断无效值、BCD解码、线性缩放（原始值乘以增益再加上偏移），并解析枚举和位域
+type Converter struct {
+	BCD     bool             // 原始值为BCD编码
+	Gain    float64          // 增益，为0时视为1
+	Offset  float64          // 偏移
+	Invalid []uint64         // 表示不可用的原始值，按寄存器的原始位比较，如0xffff、0x7fffffff
+	Enum    map[int64]string // 枚举值的名称，按BCD解码后、缩放前的整数值查找
+	Bits    map[string]uint  // 位域名称到位序号，按寄存器的原始位解析
+}
+
+// Value 转换后的数值
+type Value struct {
+	Raw   interface{}     // 解码得到的原始数值，类型同`Decode`的返回值
+	Bits  uint64          // 寄存器的原始位
+	Valid bool            // 是否有效，原始值为无效值、非法BCD或NaN时为false
+	Float float64         // 工程值
+	Name  string          // 枚举名称，未定义的枚举值为空
+	Flags map[string]bool // 位域的值
+}
+
+// IsIdentity 是否不做任何数值转换
+func (c *Converter) IsIdentity() bool {
+	return !c.BCD && (c.Gain == 0 || c.Gain == 1) && c.Offset == 0
+}
+
+func (c *Converter) gain() float64 {
+	if c.Gain == 0 {
+		return 1
+	}
+	return c.Gain
+}
+
+// 读取寄存器的原始位
+func rawBits(b []byte, t Type, o Order) uint64 {
+	switch t.Registers() {
+	case 1:
+		v, _ := Uint16s(b, o)
+		return uint64(v[0])
+	case 2:
+		v, _ := Uint32s(b, o)
+		return uint64(v[0])
+	default:
+		v, _ := Uint64s(b, o)
+		return v[0]
+	}
+}
+
+// Convert 解码寄存器数据并进行工程转换
+// 原始值无效时不返回错误，而是将`Valid`置为false
+func (c *Converter) Convert(b []byte, t Type, o Order) (Value, error) {
+	raw, err := Decode(b, t, o)
+	if err != nil {
+		return Value{}, err
+	}
+	v := Value{Raw: raw, Bits: rawBits(b, t, o)}
+
+	for _, inv := range c.Invalid {
+		if v.Bits == inv {
+			return v, nil
+		}
+	}
+
+	num, _ := ToFloat64(raw)
+	if c.BCD {
+		d, err := BCDToUint(v.Bits)
+		if err != nil {
+			return v, nil
+		}
+		num = float64(d)
+	}
+	if math.IsNaN(num) || math.IsInf(num, 0) {
+		return v, nil
+	}
+
+	v.Valid = true
+	v.Float = num*c.gain() + c.Offset
+	if c.Enum != nil {
+		v.Name = c.Enum[int64(num)]
+	}
+	if c.Bits != nil {
+		v.Flags = make(map[string]bool, len(c.Bits))
+		for name, bit := range c.Bits {
+			v.Flags[name] = v.Bits&(1<<bit) != 0
+		}
+	}
+	return v, nil
+}
+
+// EncodeValue 将工程值还原为原始值并编码为寄存器
+func (c *Converter) EncodeValue(f float64, t Type, o Order) ([]uint16, error) {
+	num := (f - c.Offset) / c.gain()
+	if !c.BCD {
+		return Encode(num, t, o)
+	}
+
+	if num < 0 {
+		return nil, fmt.Errorf("negative value %v cannot be bcd encoded", num)
+	}
+	bcd, err := UintToBCD(uint64(math.Round(num)), t.Registers()*4)
+	if err != nil {
+		return nil, err
+	}
+	switch t.Registers() {
+	case 1:
+		return EncodeUint16s([]uint16{uint16(bcd)}, o), nil
+	case 2:
+		return EncodeUint32s([]uint32{uint32(bcd)}, o), nil
+	default:
+		return EncodeUint64s([]uint64{bcd}, o), nil
+	}
+}
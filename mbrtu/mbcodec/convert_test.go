@@ -0,0 +1,139 @@
+package mbcodec
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestBCD(t *testing.T) {
+	tests := []struct {
+		bcd    uint64
+		v      uint64
+		digits int
+	}{
+		{0x0, 0, 4},
+		{0x1234, 1234, 4},
+		{0x0099, 99, 4},
+		{0x12345678, 12345678, 8},
+		{0x9999999999999999, 9999999999999999, 16},
+	}
+	for _, tt := range tests {
+		got, err := BCDToUint(tt.bcd)
+		if err != nil || got != tt.v {
+			t.Errorf("BCDToUint(%#x) = %d, %v, want %d", tt.bcd, got, err, tt.v)
+		}
+		bcd, err := UintToBCD(tt.v, tt.digits)
+		if err != nil || bcd != tt.bcd {
+			t.Errorf("UintToBCD(%d, %d) = %#x, %v, want %#x", tt.v, tt.digits, bcd, err, tt.bcd)
+		}
+	}
+
+	for _, v := range []uint64{0x000a, 0x12f4, 0xa000} {
+		if _, err := BCDToUint(v); err == nil {
+			t.Errorf("BCDToUint(%#x), want error", v)
+		}
+	}
+	if _, err := UintToBCD(10000, 4); err == nil {
+		t.Error("UintToBCD(10000, 4), want error")
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name  string
+		c     Converter
+		regs  []uint16
+		typ   Type
+		valid bool
+		float float64
+	}{
+		{"identity", Converter{}, []uint16{1234}, Uint16, true, 1234},
+		{"signed", Converter{}, []uint16{0xfffe}, Int16, true, -2},
+		{"gain", Converter{Gain: 0.1}, []uint16{2305}, Uint16, true, 230.5},
+		{"gain and offset", Converter{Gain: 0.5, Offset: -40}, []uint16{100}, Uint16, true, 10},
+		{"bcd", Converter{BCD: true}, []uint16{0x1234}, Uint16, true, 1234},
+		{"bcd scaled", Converter{BCD: true, Gain: 0.01}, []uint16{0x0001, 0x2345}, Uint32, true, 123.45},
+		{"invalid bcd", Converter{BCD: true}, []uint16{0x12ab}, Uint16, false, 0},
+		{"sentinel", Converter{Invalid: []uint64{0xffff}}, []uint16{0xffff}, Int16, false, 0},
+		{"sentinel 32", Converter{Invalid: []uint64{0x7fffffff}}, []uint16{0x7fff, 0xffff}, Int32, false, 0},
+		{"nan", Converter{}, EncodeFloat32s([]float32{float32(math.NaN())}, ABCD), Float32, false, 0},
+	}
+	for _, tt := range tests {
+		v, err := tt.c.Convert(Bytes(tt.regs), tt.typ, ABCD)
+		if err != nil {
+			t.Errorf("%s: error: %v", tt.name, err)
+			continue
+		}
+		if v.Valid != tt.valid || (tt.valid && math.Abs(v.Float-tt.float) > 1e-9) {
+			t.Errorf("%s: got valid %t float %v, want %t %v", tt.name, v.Valid, v.Float, tt.valid, tt.float)
+		}
+	}
+
+	if _, err := (&Converter{}).Convert([]byte{1, 2}, Uint32, ABCD); err == nil {
+		t.Error("Convert with short data, want error")
+	}
+}
+
+func TestConvertEnumBits(t *testing.T) {
+	c := &Converter{
+		Enum: map[int64]string{0: "off", 1: "on"},
+		Bits: map[string]uint{"alarm": 0, "fault": 15},
+	}
+	v, err := c.Convert(Bytes([]uint16{0x8001}), Uint16, ABCD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Name != "" {
+		t.Errorf("undefined enum name = %q, want empty", v.Name)
+	}
+	if want := map[string]bool{"alarm": true, "fault": true}; !reflect.DeepEqual(v.Flags, want) {
+		t.Errorf("Flags = %v, want %v", v.Flags, want)
+	}
+
+	v, err = c.Convert(Bytes([]uint16{1}), Uint16, ABCD)
+	if err != nil || v.Name != "on" {
+		t.Errorf("enum name = %q, %v, want on", v.Name, err)
+	}
+	if want := map[string]bool{"alarm": true, "fault": false}; !reflect.DeepEqual(v.Flags, want) {
+		t.Errorf("Flags = %v, want %v", v.Flags, want)
+	}
+}
+
+func TestEncodeValue(t *testing.T) {
+	tests := []struct {
+		name string
+		c    Converter
+		f    float64
+		typ  Type
+		o    Order
+	}{
+		{"identity", Converter{}, 1234, Uint16, ABCD},
+		{"gain", Converter{Gain: 0.1}, 230.5, Uint16, ABCD},
+		{"offset", Converter{Gain: 0.5, Offset: -40}, -20, Int16, ABCD},
+		{"bcd", Converter{BCD: true}, 1234, Uint16, ABCD},
+		{"bcd 32", Converter{BCD: true, Gain: 0.01}, 123.45, Uint32, CDAB},
+		{"float", Converter{Gain: 2}, 3, Float32, DCBA},
+	}
+	for _, tt := range tests {
+		regs, err := tt.c.EncodeValue(tt.f, tt.typ, tt.o)
+		if err != nil {
+			t.Errorf("%s: EncodeValue error: %v", tt.name, err)
+			continue
+		}
+		v, err := tt.c.Convert(Bytes(regs), tt.typ, tt.o)
+		if err != nil || !v.Valid || math.Abs(v.Float-tt.f) > 1e-9 {
+			t.Errorf("%s: round trip = %+v, %v, want %v", tt.name, v, err, tt.f)
+		}
+	}
+
+	if _, err := (&Converter{BCD: true}).EncodeValue(-1, Uint16, ABCD); err == nil {
+		t.Error("negative bcd, want error")
+	}
+	if _, err := (&Converter{BCD: true}).EncodeValue(10000, Uint16, ABCD); err == nil {
+		t.Error("bcd overflow, want error")
+	}
+	if _, err := (&Converter{}).EncodeValue(70000, Uint16, ABCD); err == nil {
+		t.Error("uint16 overflow, want error")
+	}
+}
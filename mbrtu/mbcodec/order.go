@@ -0,0 +1,89 @@
+package mbcodec
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Order 多字节数值在寄存器中的排列顺序
+// 以32位数值大端排列的4个字节ABCD为基准，64位数值按同样的规则扩展
+type Order byte
+
+// 32位数值的排列顺序
+const (
+	ABCD Order = iota // 大端，高位寄存器在前
+	CDAB              // 寄存器顺序交换
+	BADC              // 寄存器内字节交换
+	DCBA              // 小端，寄存器顺序和寄存器内字节都交换
+)
+
+// 64位数值的排列顺序，与32位的规则相同
+const (
+	ABCDEFGH = ABCD
+	GHEFCDAB = CDAB
+	BADCFEHG = BADC
+	HGFEDCBA = DCBA
+)
+
+var orderNames = map[string]Order{
+	"abcd":     ABCD,
+	"cdab":     CDAB,
+	"badc":     BADC,
+	"dcba":     DCBA,
+	"abcdefgh": ABCDEFGH,
+	"ghefcdab": GHEFCDAB,
+	"badcfehg": BADCFEHG,
+	"hgfedcba": HGFEDCBA,
+}
+
+// ParseOrder 解析排列顺序的名称
+// 不区分大小写，支持32位和64位的名称
+func ParseOrder(s string) (Order, error) {
+	o, ok := orderNames[strings.ToLower(s)]
+	if !ok {
+		return 0, fmt.Errorf("invalid order `%s`", s)
+	}
+	return o, nil
+}
+
+func (o Order) String() string {
+	switch o {
+	case ABCD:
+		return "ABCD"
+	case CDAB:
+		return "CDAB"
+	case BADC:
+		return "BADC"
+	case DCBA:
+		return "DCBA"
+	default:
+		return fmt.Sprintf("Order(%d)", byte(o))
+	}
+}
+
+// 寄存器顺序是否交换
+func (o Order) swapWords() bool {
+	return o == CDAB || o == DCBA
+}
+
+// 寄存器内字节是否交换
+func (o Order) swapBytes() bool {
+	return o == BADC || o == DCBA
+}
+
+// 在大端排列和指定排列之间转换单个数值的字节
+// 转换是对称的，编码和解码使用同一个函数
+func (o Order) arrange(dst, src []byte) {
+	n := len(src)
+	for i := 0; i < n; i += 2 {
+		j := i
+		if o.swapWords() {
+			j = n - 2 - i
+		}
+		if o.swapBytes() {
+			dst[j], dst[j+1] = src[i+1], src[i]
+		} else {
+			dst[j], dst[j+1] = src[i], src[i+1]
+		}
+	}
+}
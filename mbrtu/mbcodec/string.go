@@ -0,0 +1,181 @@
+package mbcodec
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// Charset 寄存器中字符串的字符集
+type Charset string
+
+// 支持的字符集
+const (
+	ASCII   Charset = "ascii"
+	UTF8    Charset = "utf8"
+	GBK     Charset = "gbk"
+	UTF16BE Charset = "utf16be"
+	UTF16LE Charset = "utf16le"
+)
+
+// ParseCharset 解析字符集的名称
+// 不区分大小写，忽略`-`和`_`
+func ParseCharset(s string) (Charset, error) {
+	c := Charset(strings.NewReplacer("-", "", "_", "").Replace(strings.ToLower(s)))
+	switch c {
+	case ASCII, UTF8, GBK, UTF16BE, UTF16LE:
+		return c, nil
+	case "utf16":
+		return UTF16BE, nil
+	default:
+		return "", fmt.Errorf("invalid charset `%s`", s)
+	}
+}
+
+// 字符集的编码单元字节数
+func (c Charset) unitSize() int {
+	if c == UTF16BE || c == UTF16LE {
+		return 2
+	}
+	return 1
+}
+
+// StringOptions 字符串编解码选项
+type StringOptions struct {
+	Charset   Charset // 字符集，为空时使用ASCII
+	SwapBytes bool    // 寄存器内的两个字节是否交换
+	Pad       byte    // 编码时的填充字节，解码时也会去掉末尾的这个字节
+}
+
+func (o StringOptions) charset() Charset {
+	if o.Charset == "" {
+		return ASCII
+	}
+	return o.Charset
+}
+
+// 交换寄存器内的两个字节
+func swapPairs(b []byte) []byte {
+	res := make([]byte, len(b))
+	for i := 0; i+1 < len(b); i += 2 {
+		res[i], res[i+1] = b[i+1], b[i]
+	}
+	if len(b)%2 != 0 {
+		res[len(b)-1] = b[len(b)-1]
+	}
+	return res
+}
+
+// String 将寄存器数据解码为字符串
+// 在第一个结束符（0）处截断，并去掉末尾的填充字节和空格
+func String(b []byte, opt StringOptions) (string, error) {
+	cs := opt.charset()
+	if opt.SwapBytes {
+		b = swapPairs(b)
+	}
+
+	// 按编码单元查找结束符
+	unit := cs.unitSize()
+	end := len(b) - len(b)%unit
+	for i := 0; i < end; i += unit {
+		if bytes.Equal(b[i:i+unit], make([]byte, unit)) {
+			end = i
+			break
+		}
+	}
+	b = b[:end]
+	// 编码时按字节填充，多字节字符集的填充编码单元的每个字节都是填充字节
+	if opt.Pad != 0 {
+		pad := bytes.Repeat([]byte{opt.Pad}, unit)
+		for len(b) >= unit && bytes.Equal(b[len(b)-unit:], pad) {
+			b = b[:len(b)-unit]
+		}
+	}
+
+	var s string
+	switch cs {
+	case ASCII:
+		for _, c := range b {
+			if c >= utf8.RuneSelf {
+				return "", fmt.Errorf("invalid ascii byte %x", c)
+			}
+		}
+		s = string(b)
+	case UTF8:
+		if !utf8.Valid(b) {
+			return "", fmt.Errorf("invalid utf8 string")
+		}
+		s = string(b)
+	case GBK:
+		res, err := simplifiedchinese.GBK.NewDecoder().Bytes(b)
+		if err != nil {
+			return "", err
+		}
+		s = string(res)
+	case UTF16BE, UTF16LE:
+		units := make([]uint16, len(b)/2)
+		for i := range units {
+			if cs == UTF16BE {
+				units[i] = uint16(b[i*2])<<8 | uint16(b[i*2+1])
+			} else {
+				units[i] = uint16(b[i*2+1])<<8 | uint16(b[i*2])
+			}
+		}
+		s = string(utf16.Decode(units))
+	default:
+		return "", fmt.Errorf("invalid charset `%s`", cs)
+	}
+	return strings.TrimRight(s, " "), nil
+}
+
+// EncodeString 将字符串编码为固定个数的寄存器
+// 长度不足时补齐填充字节，超出`regs`个寄存器时返回错误
+func EncodeString(s string, regs int, opt StringOptions) ([]uint16, error) {
+	cs := opt.charset()
+
+	var b []byte
+	switch cs {
+	case ASCII:
+		for _, c := range s {
+			if c >= utf8.RuneSelf {
+				return nil, fmt.Errorf("invalid ascii character %q", c)
+			}
+		}
+		b = []byte(s)
+	case UTF8:
+		b = []byte(s)
+	case GBK:
+		res, err := simplifiedchinese.GBK.NewEncoder().String(s)
+		if err != nil {
+			return nil, err
+		}
+		b = []byte(res)
+	case UTF16BE, UTF16LE:
+		units := utf16.Encode([]rune(s))
+		b = make([]byte, len(units)*2)
+		for i, u := range units {
+			if cs == UTF16BE {
+				b[i*2], b[i*2+1] = byte(u>>8), byte(u)
+			} else {
+				b[i*2], b[i*2+1] = byte(u), byte(u>>8)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("invalid charset `%s`", cs)
+	}
+
+	if len(b) > regs*2 {
+		return nil, fmt.Errorf("string requires %d bytes, exceeds %d registers", len(b), regs)
+	}
+	for len(b) < regs*2 {
+		b = append(b, opt.Pad)
+	}
+	if opt.SwapBytes {
+		b = swapPairs(b)
+	}
+	return Registers(b), nil
+}
@@ -0,0 +1,101 @@
+package mbcodec
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStringRoundTrip(t *testing.T) {
+	tests := []struct {
+		s   string
+		opt StringOptions
+	}{
+		{"PM5560", StringOptions{}},
+		{"PM5560", StringOptions{SwapBytes: true}},
+		{"PM5560", StringOptions{Pad: ' '}},
+		{"odd", StringOptions{Pad: 0xff}},
+		{"电表A1", StringOptions{Charset: UTF8}},
+		{"电表A1", StringOptions{Charset: GBK}},
+		{"电表A1", StringOptions{Charset: GBK, SwapBytes: true}},
+		{"电表A1", StringOptions{Charset: UTF16BE}},
+		{"电表A1", StringOptions{Charset: UTF16LE}},
+		{"电表A1", StringOptions{Charset: UTF16BE, Pad: ' '}},
+		{"电表A1", StringOptions{Charset: UTF16LE, Pad: 0xff, SwapBytes: true}},
+		{"", StringOptions{Charset: UTF16BE, Pad: ' '}},
+	}
+	for _, tt := range tests {
+		regs, err := EncodeString(tt.s, 8, tt.opt)
+		if err != nil {
+			t.Errorf("EncodeString(%q, %+v) error: %v", tt.s, tt.opt, err)
+			continue
+		}
+		if len(regs) != 8 {
+			t.Errorf("EncodeString(%q, %+v) = %d registers, want 8", tt.s, tt.opt, len(regs))
+		}
+		got, err := String(Bytes(regs), tt.opt)
+		if err != nil || got != tt.s {
+			t.Errorf("String(EncodeString(%q), %+v) = %q, %v", tt.s, tt.opt, got, err)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		b    []byte
+		opt  StringOptions
+		want string
+	}{
+		{[]byte("AB\x00CD"), StringOptions{}, "AB"},
+		{[]byte("AB  "), StringOptions{}, "AB"},
+		{[]byte("BADC"), StringOptions{SwapBytes: true}, "ABCD"},
+		{[]byte{0, 'A', 0, 'B', 0, 0, 0, 'C'}, StringOptions{Charset: UTF16BE}, "AB"},
+		{[]byte{'A', 0, 'B', 0}, StringOptions{Charset: UTF16LE}, "AB"},
+		{[]byte{0xb5, 0xe7, 0xb1, 0xed}, StringOptions{Charset: GBK}, "电表"},
+	}
+	for _, tt := range tests {
+		got, err := String(tt.b, tt.opt)
+		if err != nil || got != tt.want {
+			t.Errorf("String(%x, %+v) = %q, %v, want %q", tt.b, tt.opt, got, err, tt.want)
+		}
+	}
+
+	if _, err := String([]byte{'A', 0x80}, StringOptions{}); err == nil {
+		t.Error("String with non-ascii byte, want error")
+	}
+	if _, err := String([]byte{0xff, 0xfe}, StringOptions{Charset: UTF8}); err == nil {
+		t.Error("String with invalid utf8, want error")
+	}
+}
+
+func TestEncodeString(t *testing.T) {
+	regs, err := EncodeString("ABC", 2, StringOptions{})
+	if err != nil || !reflect.DeepEqual(regs, []uint16{0x4142, 0x4300}) {
+		t.Errorf("EncodeString = %04x, %v", regs, err)
+	}
+	if _, err := EncodeString("ABCDE", 2, StringOptions{}); err == nil {
+		t.Error("EncodeString exceeding registers, want error")
+	}
+	if _, err := EncodeString("电", 4, StringOptions{}); err == nil {
+		t.Error("EncodeString non-ascii as ascii, want error")
+	}
+}
+
+func TestParseCharset(t *testing.T) {
+	tests := map[string]Charset{
+		"ASCII":    ASCII,
+		"utf-8":    UTF8,
+		"GBK":      GBK,
+		"utf16":    UTF16BE,
+		"UTF-16LE": UTF16LE,
+		"utf_16be": UTF16BE,
+	}
+	for in, want := range tests {
+		got, err := ParseCharset(in)
+		if err != nil || got != want {
+			t.Errorf("ParseCharset(%q) = %s, %v, want %s", in, got, err, want)
+		}
+	}
+	if _, err := ParseCharset("latin1"); err == nil {
+		t.Error("ParseCharset(latin1), want error")
+	}
+}
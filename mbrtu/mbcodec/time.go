@@ -0,0 +1,172 @@
+package mbcodec
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeLayout 时间在寄存器中的格式
+type TimeLayout byte
+
+// 支持的时间格式
+const (
+	TimeUnix   TimeLayout = iota // Unix时间戳（秒），2个寄存器，按排列顺序解析
+	TimePacked                   // 年、月、日、时、分、秒各1个字节，年为2000年起的偏移，3个寄存器
+	TimeBCD                      // 同`TimePacked`，每个字节为BCD编码，3个寄存器
+	TimeCP56                     // IEC 60870-5 CP56Time2a，7个字节，4个寄存器，最后一个字节补0
+)
+
+var timeLayoutNames = map[string]TimeLayout{
+	"unix":   TimeUnix,
+	"packed": TimePacked,
+	"bcd":    TimeBCD,
+	"cp56":   TimeCP56,
+}
+
+// ParseTimeLayout 解析时间格式的名称
+// 支持unix、packed、bcd和cp56，不区分大小写
+func ParseTimeLayout(s string) (TimeLayout, error) {
+	l, ok := timeLayoutNames[strings.ToLower(s)]
+	if !ok {
+		return 0, fmt.Errorf("invalid time layout `%s`", s)
+	}
+	return l, nil
+}
+
+// Registers 时间占用的寄存器个数
+func (l TimeLayout) Registers() int {
+	switch l {
+	case TimeUnix:
+		return 2
+	case TimePacked, TimeBCD:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// 按排列顺序中的字节交换规则整理逐字节的时间格式
+// 逐字节的格式不受寄存器顺序交换的影响
+func (o Order) timeBytes(b []byte) []byte {
+	if o.swapBytes() {
+		return swapPairs(b)
+	}
+	res := make([]byte, len(b))
+	copy(res, b)
+	return res
+}
+
+// DecodeTime 将寄存器数据解码为时间
+// 不含时区的格式按`loc`解析，`loc` 为nil时使用本地时区
+func DecodeTime(b []byte, l TimeLayout, o Order, loc *time.Location) (time.Time, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	if len(b) != l.Registers()*2 {
+		return time.Time{}, fmt.Errorf("time layout requires %d bytes, got %d", l.Registers()*2, len(b))
+	}
+
+	switch l {
+	case TimeUnix:
+		v, err := Uint32s(b, o)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(int64(v[0]), 0).In(loc), nil
+
+	case TimePacked, TimeBCD:
+		f := o.timeBytes(b)
+		if l == TimeBCD {
+			for i, v := range f {
+				d, err := BCDToUint(uint64(v))
+				if err != nil {
+					return time.Time{}, err
+				}
+				f[i] = byte(d)
+			}
+		}
+		return checkedDate(2000+int(f[0]), f[1], f[2], f[3], f[4], f[5], 0, loc)
+
+	case TimeCP56:
+		f := o.timeBytes(b)
+		if f[2]&0x80 != 0 {
+			return time.Time{}, fmt.Errorf("cp56time2a is marked invalid")
+		}
+		ms := int(binary.LittleEndian.Uint16(f))
+		return checkedDate(2000+int(f[6]&0x7f), f[5]&0x0f, f[4]&0x1f, f[3]&0x1f, f[2]&0x3f, byte(ms/1000), ms%1000, loc)
+
+	default:
+		return time.Time{}, fmt.Errorf("invalid time layout %d", l)
+	}
+}
+
+// 检查日期时间各字段的范围后构造时间
+func checkedDate(year int, month, day, hour, min, sec byte, ms int, loc *time.Location) (time.Time, error) {
+	if month < 1 || month > 12 || day < 1 || day > 31 || hour > 23 || min > 59 || sec > 59 {
+		return time.Time{}, fmt.Errorf("invalid date time %d-%d-%d %d:%d:%d", year, month, day, hour, min, sec)
+	}
+	t := time.Date(year, time.Month(month), int(day), int(hour), int(min), int(sec), ms*int(time.Millisecond), loc)
+	if t.Day() != int(day) {
+		return time.Time{}, fmt.Errorf("invalid date %d-%d-%d", year, month, day)
+	}
+	return t, nil
+}
+
+// EncodeTime 将时间编码为寄存器
+// 不含时区的格式按`loc`转换，`loc` 为nil时使用本地时区
+func EncodeTime(t time.Time, l TimeLayout, o Order, loc *time.Location) ([]uint16, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	t = t.In(loc)
+
+	switch l {
+	case TimeUnix:
+		sec := t.Unix()
+		if sec < 0 || sec > 0xffffffff {
+			return nil, fmt.Errorf("time %s out of unix32 range", t)
+		}
+		return EncodeUint32s([]uint32{uint32(sec)}, o), nil
+
+	case TimePacked, TimeBCD:
+		year := t.Year() - 2000
+		if year < 0 || year > 99 {
+			return nil, fmt.Errorf("year %d out of range 2000-2099", t.Year())
+		}
+		f := []byte{byte(year), byte(t.Month()), byte(t.Day()), byte(t.Hour()), byte(t.Minute()), byte(t.Second())}
+		if l == TimeBCD {
+			for i, v := range f {
+				d, _ := UintToBCD(uint64(v), 2)
+				f[i] = byte(d)
+			}
+		}
+		return Registers(o.timeBytes(f)), nil
+
+	case TimeCP56:
+		year := t.Year() - 2000
+		if year < 0 || year > 99 {
+			return nil, fmt.Errorf("year %d out of range 2000-2099", t.Year())
+		}
+		f := make([]byte, 8)
+		binary.LittleEndian.PutUint16(f, uint16(t.Second()*1000+t.Nanosecond()/int(time.Millisecond)))
+		f[2] = byte(t.Minute())
+		f[3] = byte(t.Hour())
+		if t.IsDST() {
+			f[3] |= 0x80
+		}
+		// 星期一为1，星期日为7
+		weekday := int(t.Weekday())
+		if weekday == 0 {
+			weekday = 7
+		}
+		f[4] = byte(t.Day()) | byte(weekday)<<5
+		f[5] = byte(t.Month())
+		f[6] = byte(year)
+		return Registers(o.timeBytes(f)), nil
+
+	default:
+		return nil, fmt.Errorf("invalid time layout %d", l)
+	}
+}
@@ -0,0 +1,90 @@
+package mbcodec
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEncodeTime(t *testing.T) {
+	tm := time.Date(2024, 3, 15, 13, 45, 30, 250*int(time.Millisecond), time.UTC)
+	sec := uint32(tm.Unix())
+	tests := []struct {
+		l    TimeLayout
+		o    Order
+		want []uint16
+	}{
+		{TimeUnix, ABCD, []uint16{uint16(sec >> 16), uint16(sec)}},
+		{TimeUnix, CDAB, []uint16{uint16(sec), uint16(sec >> 16)}},
+		{TimePacked, ABCD, []uint16{0x1803, 0x0f0d, 0x2d1e}},
+		{TimePacked, CDAB, []uint16{0x1803, 0x0f0d, 0x2d1e}},
+		{TimePacked, BADC, []uint16{0x0318, 0x0d0f, 0x1e2d}},
+		{TimeBCD, ABCD, []uint16{0x2403, 0x1513, 0x4530}},
+		// 毫秒30250、分45、时13、日15（星期五）、月3、年24
+		{TimeCP56, ABCD, []uint16{0x2a76, 0x2d0d, 0xaf03, 0x1800}},
+		{TimeCP56, DCBA, []uint16{0x762a, 0x0d2d, 0x03af, 0x0018}},
+	}
+	for _, tt := range tests {
+		got, err := EncodeTime(tm, tt.l, tt.o, time.UTC)
+		if err != nil {
+			t.Errorf("EncodeTime(%d, %s) error: %v", tt.l, tt.o, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("EncodeTime(%d, %s) = %04x, want %04x", tt.l, tt.o, got, tt.want)
+		}
+	}
+}
+
+func TestTimeRoundTrip(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	tm := time.Date(2031, 12, 31, 23, 59, 58, 0, loc)
+	for _, l := range []TimeLayout{TimeUnix, TimePacked, TimeBCD, TimeCP56} {
+		for _, o := range orders {
+			regs, err := EncodeTime(tm, l, o, loc)
+			if err != nil {
+				t.Fatalf("EncodeTime(%d, %s) error: %v", l, o, err)
+			}
+			if len(regs) != l.Registers() {
+				t.Errorf("EncodeTime(%d, %s) = %d registers, want %d", l, o, len(regs), l.Registers())
+			}
+			got, err := DecodeTime(Bytes(regs), l, o, loc)
+			if err != nil || !got.Equal(tm) {
+				t.Errorf("DecodeTime(%d, %s) = %s, %v, want %s", l, o, got, err, tm)
+			}
+		}
+	}
+
+	// CP56Time2a保留毫秒
+	tm = time.Date(2024, 2, 29, 0, 0, 1, 999*int(time.Millisecond), time.UTC)
+	regs, err := EncodeTime(tm, TimeCP56, ABCD, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := DecodeTime(Bytes(regs), TimeCP56, ABCD, time.UTC); err != nil || !got.Equal(tm) {
+		t.Errorf("cp56 millisecond round trip = %s, %v, want %s", got, err, tm)
+	}
+}
+
+func TestDecodeTimeInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		regs []uint16
+		l    TimeLayout
+	}{
+		{"short", []uint16{0x1803}, TimePacked},
+		{"month 13", []uint16{0x180d, 0x0f0d, 0x2d1e}, TimePacked},
+		{"feb 30", []uint16{0x1802, 0x1e00, 0x0000}, TimePacked},
+		{"invalid bcd", []uint16{0x2a03, 0x1513, 0x4530}, TimeBCD},
+		{"cp56 invalid flag", []uint16{0x2a76, 0xad0d, 0xaf03, 0x1800}, TimeCP56},
+	}
+	for _, tt := range tests {
+		if got, err := DecodeTime(Bytes(tt.regs), tt.l, ABCD, time.UTC); err == nil {
+			t.Errorf("%s: got %s, want error", tt.name, got)
+		}
+	}
+
+	if _, err := EncodeTime(time.Date(1999, 1, 1, 0, 0, 0, 0, time.UTC), TimePacked, ABCD, time.UTC); err == nil {
+		t.Error("EncodeTime year 1999 as packed, want error")
+	}
+}
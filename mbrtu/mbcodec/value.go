@@ -0,0 +1,227 @@
+package mbcodec
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Type 单个数值的类型
+type Type string
+
+// 支持的数值类型
+const (
+	Bool    Type = "bool"
+	Int16   Type = "int16"
+	Uint16  Type = "uint16"
+	Int32   Type = "int32"
+	Uint32  Type = "uint32"
+	Int64   Type = "int64"
+	Uint64  Type = "uint64"
+	Float32 Type = "float32"
+	Float64 Type = "float64"
+)
+
+var typeRegs = map[Type]int{
+	Bool:    1,
+	Int16:   1,
+	Uint16:  1,
+	Int32:   2,
+	Uint32:  2,
+	Int64:   4,
+	Uint64:  4,
+	Float32: 2,
+	Float64: 4,
+}
+
+// ParseType 解析数值类型的名称
+// 不区分大小写
+func ParseType(s string) (Type, error) {
+	t := Type(strings.ToLower(s))
+	if _, ok := typeRegs[t]; !ok {
+		return "", fmt.Errorf("invalid type `%s`", s)
+	}
+	return t, nil
+}
+
+// Registers 数值占用的寄存器个数
+func (t Type) Registers() int {
+	return typeRegs[t]
+}
+
+// Decode 将寄存器数据解码为单个数值
+// 布尔返回bool（寄存器非0为真），有符号整数返回int64，无符号整数返回uint64，浮点数返回float64
+func Decode(b []byte, t Type, o Order) (interface{}, error) {
+	size := t.Registers() * 2
+	if size == 0 {
+		return nil, fmt.Errorf("invalid type `%s`", t)
+	}
+	if len(b) != size {
+		return nil, fmt.Errorf("type %s requires %d bytes, got %d", t, size, len(b))
+	}
+
+	switch t {
+	case Bool:
+		v, _ := Uint16s(b, o)
+		return v[0] != 0, nil
+	case Int16:
+		v, _ := Int16s(b, o)
+		return int64(v[0]), nil
+	case Uint16:
+		v, _ := Uint16s(b, o)
+		return uint64(v[0]), nil
+	case Int32:
+		v, _ := Int32s(b, o)
+		return int64(v[0]), nil
+	case Uint32:
+		v, _ := Uint32s(b, o)
+		return uint64(v[0]), nil
+	case Int64:
+		v, _ := Int64s(b, o)
+		return v[0], nil
+	case Uint64:
+		v, _ := Uint64s(b, o)
+		return v[0], nil
+	case Float32:
+		v, _ := Float32s(b, o)
+		return float64(v[0]), nil
+	default:
+		v, _ := Float64s(b, o)
+		return v[0], nil
+	}
+}
+
+// Encode 将单个数值编码为寄存器
+// `v` 可以是布尔或任意整数、浮点数，按`t`转换，整数溢出时返回错误
+func Encode(v interface{}, t Type, o Order) ([]uint16, error) {
+	if t == Bool {
+		on, ok := v.(bool)
+		if !ok {
+			f, ok := ToFloat64(v)
+			if !ok {
+				return nil, fmt.Errorf("cannot encode %T as %s", v, t)
+			}
+			on = f != 0
+		}
+		if on {
+			return []uint16{1}, nil
+		}
+		return []uint16{0}, nil
+	}
+
+	f, ok := ToFloat64(v)
+	if !ok {
+		return nil, fmt.Errorf("cannot encode %T as %s", v, t)
+	}
+
+	switch t {
+	case Float32:
+		return EncodeFloat32s([]float32{float32(f)}, o), nil
+	case Float64:
+		return EncodeFloat64s([]float64{f}, o), nil
+	}
+
+	// 整数优先使用原始值，避免大整数经过浮点数丢失精度
+	var i int64
+	var u uint64
+	var big bool // 超过int64的范围
+	switch x := v.(type) {
+	case int:
+		i, u = int64(x), uint64(x)
+	case int64:
+		i, u = x, uint64(x)
+	case uint:
+		i, u, big = int64(x), uint64(x), uint64(x) > math.MaxInt64
+	case uint64:
+		i, u, big = int64(x), x, x > math.MaxInt64
+	default:
+		// 超出范围的浮点数转换为整数的结果不确定，需要先检查范围
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("cannot encode %v as %s", v, t)
+		}
+		f = math.Round(f)
+		if f < math.MinInt64 || f >= 1<<64 {
+			return nil, fmt.Errorf("value %v overflows %s", v, t)
+		}
+		big = f >= 1<<63
+		if big {
+			u = uint64(f)
+		} else {
+			i = int64(f)
+			u = uint64(i)
+		}
+	}
+	neg := f < 0
+
+	switch t {
+	case Int16:
+		if f < math.MinInt16 || f > math.MaxInt16 {
+			return nil, fmt.Errorf("value %v overflows %s", v, t)
+		}
+		return EncodeInt16s([]int16{int16(i)}, o), nil
+	case Uint16:
+		if neg || f > math.MaxUint16 {
+			return nil, fmt.Errorf("value %v overflows %s", v, t)
+		}
+		return EncodeUint16s([]uint16{uint16(u)}, o), nil
+	case Int32:
+		if f < math.MinInt32 || f > math.MaxInt32 {
+			return nil, fmt.Errorf("value %v overflows %s", v, t)
+		}
+		return EncodeInt32s([]int32{int32(i)}, o), nil
+	case Uint32:
+		if neg || f > math.MaxUint32 {
+			return nil, fmt.Errorf("value %v overflows %s", v, t)
+		}
+		return EncodeUint32s([]uint32{uint32(u)}, o), nil
+	case Int64:
+		if big {
+			return nil, fmt.Errorf("value %v overflows %s", v, t)
+		}
+		return EncodeInt64s([]int64{i}, o), nil
+	case Uint64:
+		if neg {
+			return nil, fmt.Errorf("value %v overflows %s", v, t)
+		}
+		return EncodeUint64s([]uint64{u}, o), nil
+	default:
+		return nil, fmt.Errorf("invalid type `%s`", t)
+	}
+}
+
+// ToFloat64 将布尔或任意整数、浮点数转换为float64
+func ToFloat64(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case bool:
+		if x {
+			return 1, true
+		}
+		return 0, true
+	case int:
+		return float64(x), true
+	case int8:
+		return float64(x), true
+	case int16:
+		return float64(x), true
+	case int32:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case uint:
+		return float64(x), true
+	case uint8:
+		return float64(x), true
+	case uint16:
+		return float64(x), true
+	case uint32:
+		return float64(x), true
+	case uint64:
+		return float64(x), true
+	case float32:
+		return float64(x), true
+	case float64:
+		return x, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,71 @@
+package mbcodec
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		v    interface{}
+		t    Type
+		want interface{}
+	}{
+		{true, Bool, true},
+		{int8(-3), Int16, int64(-3)},
+		{1.5, Int16, int64(2)},
+		{-2.5, Int32, int64(-3)},
+		{uint(65535), Uint16, uint64(65535)},
+		{int64(math.MaxInt64), Int64, int64(math.MaxInt64)},
+		{int64(math.MinInt64), Int64, int64(math.MinInt64)},
+		{float64(math.MinInt64), Int64, int64(math.MinInt64)},
+		{uint64(math.MaxInt64), Int64, int64(math.MaxInt64)},
+		{uint64(math.MaxUint64), Uint64, uint64(math.MaxUint64)},
+		{1e19, Uint64, uint64(1e19)},
+		{-1.25, Float64, -1.25},
+	}
+	for _, tt := range tests {
+		regs, err := Encode(tt.v, tt.t, CDAB)
+		if err != nil {
+			t.Errorf("Encode(%v, %s) error: %v", tt.v, tt.t, err)
+			continue
+		}
+		got, err := Decode(Bytes(regs), tt.t, CDAB)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Encode(%v, %s) decodes to %v, %v, want %v", tt.v, tt.t, got, err, tt.want)
+		}
+	}
+}
+
+func TestEncodeOverflow(t *testing.T) {
+	tests := []struct {
+		v interface{}
+		t Type
+	}{
+		{32768, Int16},
+		{-1, Uint16},
+		{uint64(math.MaxUint64), Int64},
+		{1e20, Int64},
+		{-1e20, Int64},
+		{float64(1 << 63), Int64},
+		{1e20, Uint64},
+		{float64(1 << 64), Uint64},
+		{-1, Uint64},
+		{math.NaN(), Int16},
+		{math.NaN(), Uint64},
+		{math.Inf(1), Int64},
+		{math.Inf(-1), Int32},
+		{"1", Uint16},
+	}
+	for _, tt := range tests {
+		if regs, err := Encode(tt.v, tt.t, ABCD); err == nil {
+			t.Errorf("Encode(%v, %s) = %04x, want error", tt.v, tt.t, regs)
+		}
+	}
+
+	// 浮点数类型可以编码NaN
+	if _, err := Encode(math.NaN(), Float32, ABCD); err != nil {
+		t.Errorf("Encode(NaN, float32) error: %v", err)
+	}
+}
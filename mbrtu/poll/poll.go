@@ -0,0 +1,319 @@
+// Package poll 周期轮询
+// 在一个主站上按各自的周期轮询多个轮询组，记录每组的最新数据、时间和质量，并通过回调或通道通知
+package poll
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"ckklearn.com/testmodbus/global"
+	"ckklearn.com/testmodbus/mbrtu"
+)
+
+// Quality 数据质量
+type Quality byte
+
+// 数据质量
+const (
+	QualityUncertain Quality = iota // 尚未读取成功过
+	QualityGood                     // 最近一次读取成功
+	QualityBad                      // 最近一次读取失败，数据为上次成功读取的值，从未成功时为空
+)
+
+func (q Quality) String() string {
+	switch q {
+	case QualityUncertain:
+		return "uncertain"
+	case QualityGood:
+		return "good"
+	case QualityBad:
+		return "bad"
+	default:
+		return fmt.Sprintf("Quality(%d)", byte(q))
+	}
+}
+
+// Group 轮询组
+// 每次轮询用一个读请求读取从站一段连续的地址
+type Group struct {
+	Name     string
+	Slave    byte
+	Fun      global.FunCode   // 读取功能码，0x01~0x04
+	Offset   uint16           // 起始偏移量
+	Num      uint16           // 读取的个数
+	Interval time.Duration    // 轮询周期
+	CrcOrder binary.ByteOrder // crc字节序，为nil时使用小端
+	Handler  func(Update)     // 每次轮询后调用，可以为nil
+}
+
+// Update 一次轮询的结果
+type Update struct {
+	Group   string
+	Data    []byte    // 最近一次成功读取的数据，线圈和离散输入为按位打包的字节
+	Time    time.Time // 最近一次成功读取的时间
+	Quality Quality
+	Err     error // 本次轮询的错误
+}
+
+// 轮询组的运行状态
+type entry struct {
+	g    Group
+	area global.Area
+	due  time.Time // 下次轮询的时间
+	last time.Time // 上次轮询的时间，用于同时到期时的排序
+	upd  Update
+}
+
+// Poller 轮询器
+// 所有轮询组在同一个协程中依次执行，与其它调用方共用主站的总线锁
+type Poller struct {
+	m       *mbrtu.RtuMaster
+	l       *sync.Mutex
+	groups  map[string]*entry
+	watches map[string][]*watch
+	subs    map[chan Update]struct{}
+	wake    chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// New 构造函数
+func New(m *mbrtu.RtuMaster) *Poller {
+	return &Poller{
+		m:       m,
+		l:       new(sync.Mutex),
+		groups:  make(map[string]*entry),
+		watches: make(map[string][]*watch),
+		subs:    make(map[chan Update]struct{}),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Add 添加轮询组
+// 运行中也可以添加，新的轮询组立即开始轮询
+func (p *Poller) Add(g Group) error {
+	if g.Name == "" {
+		return fmt.Errorf("poll group requires a name")
+	}
+	area, err := global.ReadArea(g.Fun)
+	if err != nil {
+		return fmt.Errorf("poll group %s: %v", g.Name, err)
+	}
+	if g.Num == 0 || g.Num > area.MaxReadNum() {
+		return fmt.Errorf("poll group %s: num must be 1~%d", g.Name, area.MaxReadNum())
+	}
+	if g.Interval <= 0 {
+		return fmt.Errorf("poll group %s: interval must be positive", g.Name)
+	}
+	if g.CrcOrder == nil {
+		g.CrcOrder = binary.LittleEndian
+	}
+
+	p.l.Lock()
+	defer p.l.Unlock()
+	if _, ok := p.groups[g.Name]; ok {
+		return fmt.Errorf("poll group %s already exists", g.Name)
+	}
+	p.groups[g.Name] = &entry{
+		g:    g,
+		area: area,
+		due:  time.Now(),
+		upd:  Update{Group: g.Name},
+	}
+	p.notify()
+	return nil
+}
+
+// Remove 移除轮询组
+// 同时取消该组的变化订阅
+func (p *Poller) Remove(name string) {
+	p.l.Lock()
+	defer p.l.Unlock()
+	delete(p.groups, name)
+	delete(p.watches, name)
+	p.notify()
+}
+
+// Last 轮询组最近一次轮询的结果
+func (p *Poller) Last(name string) (Update, bool) {
+	p.l.Lock()
+	defer p.l.Unlock()
+	e, ok := p.groups[name]
+	if !ok {
+		return Update{}, false
+	}
+	return e.upd, true
+}
+
+// Subscribe 订阅所有轮询组的结果
+// 通道已满时丢弃结果，以免阻塞轮询，`size` 为通道的缓冲大小
+func (p *Poller) Subscribe(size int) <-chan Update {
+	ch := make(chan Update, size)
+	p.l.Lock()
+	defer p.l.Unlock()
+	p.subs[ch] = struct{}{}
+	return ch
+}
+
+// Unsubscribe 取消订阅并关闭通道
+func (p *Poller) Unsubscribe(ch <-chan Update) {
+	p.l.Lock()
+	defer p.l.Unlock()
+	for c := range p.subs {
+		if c == ch {
+			delete(p.subs, c)
+			close(c)
+		}
+	}
+}
+
+// Start 开始轮询
+func (p *Poller) Start() {
+	p.l.Lock()
+	defer p.l.Unlock()
+	if p.stop != nil {
+		return
+	}
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	go p.run(p.stop, p.done)
+}
+
+// Stop 停止轮询
+// 等待正在进行的轮询完成后返回
+func (p *Poller) Stop() {
+	p.l.Lock()
+	stop, done := p.stop, p.done
+	p.stop, p.done = nil, nil
+	p.l.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// 唤醒轮询协程重新计算下次轮询的时间
+func (p *Poller) notify() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (p *Poller) run(stop, done chan struct{}) {
+	defer close(done)
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		e, wait := p.next()
+		if e != nil && wait <= 0 {
+			p.poll(e)
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			continue
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		if e == nil {
+			wait = time.Hour
+		}
+		timer.Reset(wait)
+		select {
+		case <-stop:
+			return
+		case <-p.wake:
+		case <-timer.C:
+		}
+	}
+}
+
+// 选择最早到期的轮询组
+// 到期时间相同时选择上次轮询最早的，使过载时各组轮流执行
+func (p *Poller) next() (*entry, time.Duration) {
+	p.l.Lock()
+	defer p.l.Unlock()
+	var next *entry
+	for _, e := range p.groups {
+		if next == nil || e.due.Before(next.due) ||
+			(e.due.Equal(next.due) && e.last.Before(next.last)) {
+			next = e
+		}
+	}
+	if next == nil {
+		return nil, 0
+	}
+	return next, time.Until(next.due)
+}
+
+// 记录本次轮询的时间并计算下次轮询的时间
+// 错过的周期不补读，避免总线繁忙时集中轮询
+func (e *entry) advance(now time.Time) {
+	e.last = now
+	e.due = e.due.Add(e.g.Interval)
+	if e.due.Before(now) {
+		e.due = now
+	}
+}
+
+// 执行一次轮询并分发结果
+func (p *Poller) poll(e *entry) {
+	g := &e.g
+	data, err := p.read(g, e.area)
+	now := time.Now()
+
+	p.l.Lock()
+	e.advance(now)
+	e.upd.Err = err
+	if err == nil {
+		e.upd.Data, e.upd.Time, e.upd.Quality = data, now, QualityGood
+	} else {
+		e.upd.Quality = QualityBad
+	}
+	upd := e.upd
+	// 轮询期间被移除的组不再通知
+	if p.groups[g.Name] != e {
+		p.l.Unlock()
+		return
+	}
+	for ch := range p.subs {
+		select {
+		case ch <- upd:
+		default:
+		}
+	}
+	type notice struct {
+		h  func(Event)
+		ev Event
+	}
+	var notices []notice
+	for _, ws := range p.watches[g.Name] {
+		if ev, ok := ws.check(upd, e.area.IsBit(), now); ok {
+			notices = append(notices, notice{ws.w.Handler, ev})
+		}
+	}
+	p.l.Unlock()
+
+	if g.Handler != nil {
+		g.Handler(upd)
+	}
+	for _, n := range notices {
+		n.h(n.ev)
+	}
+}
+
+// 读取轮询组的数据
+func (p *Poller) read(g *Group, area global.Area) ([]byte, error) {
+	return p.m.ReadAreaBytes(g.Slave, area, g.Offset, g.Num, g.CrcOrder)
+}
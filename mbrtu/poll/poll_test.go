@@ -0,0 +1,80 @@
+package poll
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	t0 := time.Now().Add(time.Hour)
+	p := New(nil)
+	if e, _ := p.next(); e != nil {
+		t.Fatalf("next without groups = %s", e.g.Name)
+	}
+
+	p.groups["a"] = &entry{g: Group{Name: "a"}, due: t0.Add(time.Second)}
+	p.groups["b"] = &entry{g: Group{Name: "b"}, due: t0, last: t0.Add(-time.Second)}
+	p.groups["c"] = &entry{g: Group{Name: "c"}, due: t0, last: t0.Add(-2 * time.Second)}
+	// 到期时间相同时选择上次轮询最早的
+	e, wait := p.next()
+	if e.g.Name != "c" {
+		t.Errorf("next = %s, want c", e.g.Name)
+	}
+	if wait <= 59*time.Minute || wait > time.Hour {
+		t.Errorf("wait = %s, want about 1h", wait)
+	}
+
+	p.groups["a"].due = t0.Add(-time.Millisecond)
+	if e, _ := p.next(); e.g.Name != "a" {
+		t.Errorf("next = %s, want a", e.g.Name)
+	}
+}
+
+func TestNextFair(t *testing.T) {
+	// 总线过载时所有组都已到期，各组应轮流轮询
+	t0 := time.Now().Add(-time.Hour)
+	p := New(nil)
+	for _, name := range []string{"a", "b", "c"} {
+		p.groups[name] = &entry{g: Group{Name: name, Interval: time.Second}, due: t0}
+	}
+	now := t0.Add(time.Minute)
+	var order []string
+	for i := 0; i < 9; i++ {
+		e, wait := p.next()
+		if wait > 0 {
+			t.Fatalf("poll %d: group %s not due", i, e.g.Name)
+		}
+		order = append(order, e.g.Name)
+		now = now.Add(10 * time.Millisecond)
+		e.advance(now)
+	}
+	for i := 3; i < len(order); i++ {
+		if order[i] != order[i-3] {
+			t.Fatalf("poll order %v is not round robin", order)
+		}
+	}
+	if order[0] == order[1] || order[1] == order[2] || order[0] == order[2] {
+		t.Fatalf("poll order %v is not round robin", order)
+	}
+}
+
+func TestAdvance(t *testing.T) {
+	t0 := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{"on time", t0.Add(200 * time.Millisecond), t0.Add(time.Second)},
+		{"late within cycle", t0.Add(900 * time.Millisecond), t0.Add(time.Second)},
+		// 错过的周期不补读
+		{"missed cycles", t0.Add(3500 * time.Millisecond), t0.Add(3500 * time.Millisecond)},
+	}
+	for _, tt := range tests {
+		e := &entry{g: Group{Interval: time.Second}, due: t0}
+		e.advance(tt.now)
+		if !e.due.Equal(tt.want) || !e.last.Equal(tt.now) {
+			t.Errorf("%s: due %s last %s, want %s %s", tt.name, e.due, e.last, tt.want, tt.now)
+		}
+	}
+}
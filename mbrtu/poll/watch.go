@@ -0,0 +1,197 @@
+package poll
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"ckklearn.com/testmodbus/mbrtu/mbcodec"
+)
+
+// Reason 变化通知的原因
+type Reason byte
+
+// 变化通知的原因
+const (
+	ReasonInitial   Reason = iota // 第一次读取到数值
+	ReasonChange                  // 数值变化超过死区
+	ReasonQuality                 // 数据质量变化
+	ReasonHeartbeat               // 超过最长静默时间
+)
+
+func (r Reason) String() string {
+	switch r {
+	case ReasonInitial:
+		return "initial"
+	case ReasonChange:
+		return "change"
+	case ReasonQuality:
+		return "quality"
+	case ReasonHeartbeat:
+		return "heartbeat"
+	default:
+		return fmt.Sprintf("Reason(%d)", byte(r))
+	}
+}
+
+// Watch 测点的变化订阅
+// 测点位于某个轮询组中，每次轮询后判断是否需要通知
+type Watch struct {
+	Group     string
+	Index     uint16             // 测点在轮询组中的位置（寄存器或位），从0开始
+	Type      mbcodec.Type       // 数值类型，为空时线圈和离散输入为bool，寄存器为uint16
+	Order     mbcodec.Order      // 多寄存器数值的排列顺序
+	Conv      *mbcodec.Converter // 工程转换，可以为nil
+	Deadband  float64            // 绝对死区，工程值的变化超过该值时通知
+	Percent   float64            // 百分比死区，工程值相对上次通知值的变化超过该百分比时通知
+	Heartbeat time.Duration      // 最长静默时间，超过后即使没有变化也通知，为0时不限制，精度为轮询周期
+	Handler   func(Event)
+}
+
+// Event 变化通知
+type Event struct {
+	Group   string
+	Index   uint16
+	Value   mbcodec.Value // 数据质量不好时为最近一次通知的值
+	Quality Quality
+	Time    time.Time
+	Reason  Reason
+}
+
+// 订阅的运行状态
+type watch struct {
+	w       Watch
+	sent    bool          // 是否通知过
+	last    mbcodec.Value // 最近一次通知的值
+	quality Quality       // 最近一次通知的数据质量
+	at      time.Time     // 最近一次通知的时间
+}
+
+// Watch 订阅轮询组中测点的变化
+// 两个死区都为0时数值有任何变化都通知，从站不应答或恢复应答时通知数据质量的变化
+// 返回取消订阅的函数
+func (p *Poller) Watch(w Watch) (func(), error) {
+	if w.Handler == nil {
+		return nil, fmt.Errorf("watch requires a handler")
+	}
+	if w.Conv == nil {
+		w.Conv = new(mbcodec.Converter)
+	}
+
+	p.l.Lock()
+	defer p.l.Unlock()
+	e, ok := p.groups[w.Group]
+	if !ok {
+		return nil, fmt.Errorf("unknown poll group `%s`", w.Group)
+	}
+	size := 1
+	if e.area.IsBit() {
+		if w.Type != "" && w.Type != mbcodec.Bool {
+			return nil, fmt.Errorf("area %s only supports bool", e.area)
+		}
+		w.Type = mbcodec.Bool
+	} else {
+		if w.Type == "" {
+			w.Type = mbcodec.Uint16
+		}
+		size = w.Type.Registers()
+		if size == 0 {
+			return nil, fmt.Errorf("invalid type `%s`", w.Type)
+		}
+	}
+	if int(w.Index)+size > int(e.g.Num) {
+		return nil, fmt.Errorf("index %d out of poll group %s", w.Index, w.Group)
+	}
+
+	ws := &watch{w: w}
+	p.watches[w.Group] = append(p.watches[w.Group], ws)
+	cancel := func() {
+		p.l.Lock()
+		defer p.l.Unlock()
+		list := p.watches[w.Group]
+		for i, x := range list {
+			if x == ws {
+				p.watches[w.Group] = append(list[:i:i], list[i+1:]...)
+				break
+			}
+		}
+	}
+	return cancel, nil
+}
+
+// 从轮询组的数据中解码测点的值
+func (ws *watch) decode(data []byte, bit bool) (mbcodec.Value, error) {
+	w := &ws.w
+	if bit {
+		bits, err := mbcodec.Bools(data, int(w.Index)+1)
+		if err != nil {
+			return mbcodec.Value{}, err
+		}
+		v := mbcodec.Value{Raw: bits[w.Index], Valid: true}
+		if bits[w.Index] {
+			v.Bits, v.Float = 1, 1
+		}
+		return v, nil
+	}
+	start := int(w.Index) * 2
+	return w.Conv.Convert(data[start:start+w.Type.Registers()*2], w.Type, w.Order)
+}
+
+// 数值的变化是否超过死区
+func (ws *watch) changed(v mbcodec.Value) bool {
+	last := ws.last
+	if v.Valid != last.Valid {
+		return true
+	}
+	if !v.Valid {
+		return v.Bits != last.Bits
+	}
+	diff := math.Abs(v.Float - last.Float)
+	if ws.w.Deadband == 0 && ws.w.Percent == 0 {
+		return diff != 0 || v.Bits != last.Bits
+	}
+	if ws.w.Deadband > 0 && diff > ws.w.Deadband {
+		return true
+	}
+	return ws.w.Percent > 0 && diff > math.Abs(last.Float)*ws.w.Percent/100
+}
+
+// 根据一次轮询的结果判断是否需要通知
+func (ws *watch) check(upd Update, bit bool, now time.Time) (Event, bool) {
+	ev := Event{Group: ws.w.Group, Index: ws.w.Index, Value: ws.last, Quality: upd.Quality, Time: now}
+	heartbeat := ws.sent && ws.w.Heartbeat > 0 && now.Sub(ws.at) >= ws.w.Heartbeat
+
+	switch {
+	case upd.Quality != QualityGood:
+		switch {
+		case ws.quality != upd.Quality:
+			ev.Reason = ReasonQuality
+		case heartbeat:
+			ev.Reason = ReasonHeartbeat
+		default:
+			return Event{}, false
+		}
+	default:
+		v, err := ws.decode(upd.Data, bit)
+		if err != nil {
+			return Event{}, false
+		}
+		ev.Value = v
+		switch {
+		case !ws.sent:
+			ev.Reason = ReasonInitial
+		case ws.quality != QualityGood:
+			ev.Reason = ReasonQuality
+		case ws.changed(v):
+			ev.Reason = ReasonChange
+		case heartbeat:
+			ev.Reason = ReasonHeartbeat
+		default:
+			return Event{}, false
+		}
+		ws.last = v
+		ws.sent = true
+	}
+	ws.quality, ws.at = upd.Quality, now
+	return ev, true
+}
@@ -0,0 +1,97 @@
+package poll
+
+import (
+	"testing"
+	"time"
+
+	"ckklearn.com/testmodbus/mbrtu/mbcodec"
+)
+
+func TestWatchChanged(t *testing.T) {
+	valid := func(f float64) mbcodec.Value { return mbcodec.Value{Float: f, Valid: true} }
+	tests := []struct {
+		name     string
+		deadband float64
+		percent  float64
+		last     mbcodec.Value
+		v        mbcodec.Value
+		want     bool
+	}{
+		{"any change", 0, 0, valid(100), valid(100.01), true},
+		{"no change", 0, 0, valid(100), valid(100), false},
+		{"within deadband", 5, 0, valid(100), valid(105), false},
+		{"beyond deadband", 5, 0, valid(100), valid(94.9), true},
+		{"within percent", 0, 10, valid(200), valid(219), false},
+		{"beyond percent", 0, 10, valid(200), valid(179), true},
+		{"either deadband", 50, 1, valid(200), valid(203), true},
+		{"becomes invalid", 5, 0, valid(100), mbcodec.Value{Bits: 0xffff}, true},
+		{"same invalid", 5, 0, mbcodec.Value{Bits: 0xffff}, mbcodec.Value{Bits: 0xffff}, false},
+	}
+	for _, tt := range tests {
+		ws := &watch{w: Watch{Deadband: tt.deadband, Percent: tt.percent}, last: tt.last}
+		if got := ws.changed(tt.v); got != tt.want {
+			t.Errorf("%s: changed = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWatchCheck(t *testing.T) {
+	ws := &watch{w: Watch{
+		Index:     1,
+		Type:      mbcodec.Uint16,
+		Conv:      new(mbcodec.Converter),
+		Deadband:  5,
+		Heartbeat: 10 * time.Second,
+	}}
+	good := func(v uint16) Update {
+		return Update{Data: mbcodec.Bytes([]uint16{0, v}), Quality: QualityGood}
+	}
+	bad := Update{Data: mbcodec.Bytes([]uint16{0, 106}), Quality: QualityBad}
+
+	t0 := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	steps := []struct {
+		upd    Update
+		at     time.Duration
+		notify bool
+		reason Reason
+		value  float64
+	}{
+		{good(100), 0, true, ReasonInitial, 100},
+		{good(103), time.Second, false, 0, 0},
+		{good(106), 2 * time.Second, true, ReasonChange, 106},
+		{good(108), 3 * time.Second, false, 0, 0},
+		{bad, 4 * time.Second, true, ReasonQuality, 106},
+		{bad, 5 * time.Second, false, 0, 0},
+		{good(108), 6 * time.Second, true, ReasonQuality, 108},
+		{good(109), 15 * time.Second, false, 0, 0},
+		{good(109), 16 * time.Second, true, ReasonHeartbeat, 109},
+	}
+	for i, s := range steps {
+		ev, ok := ws.check(s.upd, false, t0.Add(s.at))
+		if ok != s.notify {
+			t.Fatalf("step %d: notify = %t, want %t", i, ok, s.notify)
+		}
+		if !ok {
+			continue
+		}
+		if ev.Reason != s.reason || ev.Value.Float != s.value || ev.Quality != s.upd.Quality {
+			t.Errorf("step %d: got %s %v %v, want %s %v %v", i, ev.Reason, ev.Value.Float, ev.Quality, s.reason, s.value, s.upd.Quality)
+		}
+	}
+}
+
+func TestWatchCheckBit(t *testing.T) {
+	ws := &watch{w: Watch{Index: 9, Type: mbcodec.Bool}}
+	now := time.Now()
+	ev, ok := ws.check(Update{Data: []byte{0x00, 0x02}, Quality: QualityGood}, true, now)
+	if !ok || ev.Reason != ReasonInitial || ev.Value.Raw != true {
+		t.Fatalf("initial bit event = %+v, %t", ev, ok)
+	}
+	if _, ok := ws.check(Update{Data: []byte{0xff, 0x02}, Quality: QualityGood}, true, now); ok {
+		t.Error("other bits changed, want no event")
+	}
+	ev, ok = ws.check(Update{Data: []byte{0xff, 0x00}, Quality: QualityGood}, true, now)
+	if !ok || ev.Reason != ReasonChange || ev.Value.Raw != false {
+		t.Errorf("bit change event = %+v, %t", ev, ok)
+	}
+}
@@ -0,0 +1,134 @@
+package profile
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 解析csv格式的测点表
+// 第一行为列名，与json的字段名相同，不区分大小写，未知的列被忽略
+// invalid、enum和bits中的多项用`;`分隔，如`0xffff;0x8000`、`0=off;1=on`、`alarm=0;fault=1`
+func parseCSV(data []byte) ([]Point, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.TrimLeadingSpace = true
+	r.Comment = '#'
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("csv requires a header")
+	}
+
+	header := records[0]
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+	points := make([]Point, 0, len(records)-1)
+	for i, rec := range records[1:] {
+		var pt Point
+		for j, field := range rec {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			err := pt.setField(header[j], field)
+			if err != nil {
+				return nil, fmt.Errorf("csv line %d: column %s: %v", i+2, header[j], err)
+			}
+		}
+		points = append(points, pt)
+	}
+	return points, nil
+}
+
+// 按列名设置测点的字段
+func (pt *Point) setField(name, s string) error {
+	var err error
+	switch name {
+	case "name":
+		pt.Name = s
+	case "description":
+		pt.Description = s
+	case "address":
+		pt.Address = s
+	case "area":
+		pt.Area = s
+	case "offset":
+		var v uint64
+		v, err = strconv.ParseUint(s, 0, 16)
+		pt.Offset = uint16(v)
+	case "type":
+		pt.Type = s
+	case "order":
+		pt.Order = s
+	case "length":
+		var v uint64
+		v, err = strconv.ParseUint(s, 0, 16)
+		pt.Length = uint16(v)
+	case "charset":
+		pt.Charset = s
+	case "swap_bytes":
+		pt.SwapBytes, err = strconv.ParseBool(s)
+	case "scale":
+		pt.Scale, err = strconv.ParseFloat(s, 64)
+	case "bias":
+		pt.Bias, err = strconv.ParseFloat(s, 64)
+	case "bcd":
+		pt.BCD, err = strconv.ParseBool(s)
+	case "invalid":
+		for _, item := range strings.Split(s, ";") {
+			var v uint64
+			v, err = strconv.ParseUint(strings.TrimSpace(item), 0, 64)
+			if err != nil {
+				break
+			}
+			pt.Invalid = append(pt.Invalid, v)
+		}
+	case "enum":
+		pt.Enum = make(map[int64]string)
+		err = splitPairs(s, func(k, v string) error {
+			i, err := strconv.ParseInt(k, 0, 64)
+			pt.Enum[i] = v
+			return err
+		})
+	case "bits":
+		pt.Bits = make(map[string]uint)
+		err = splitPairs(s, func(k, v string) error {
+			bit, err := strconv.ParseUint(v, 0, 6)
+			pt.Bits[k] = uint(bit)
+			return err
+		})
+	case "unit":
+		pt.Unit = s
+	case "min":
+		var v float64
+		v, err = strconv.ParseFloat(s, 64)
+		pt.Min = &v
+	case "max":
+		var v float64
+		v, err = strconv.ParseFloat(s, 64)
+		pt.Max = &v
+	case "access":
+		pt.Access = s
+	}
+	return err
+}
+
+// 解析`k=v;k=v`格式的列
+func splitPairs(s string, f func(k, v string) error) error {
+	for _, item := range strings.Split(s, ";") {
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid item `%s`", item)
+		}
+		err := f(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
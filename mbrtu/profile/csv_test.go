@@ -0,0 +1,54 @@
+package profile
+
+import (
+	"reflect"
+	"testing"
+
+	"ckklearn.com/testmodbus/global"
+	"ckklearn.com/testmodbus/mbrtu/mbcodec"
+)
+
+func TestParseCSV(t *testing.T) {
+	src := `Name, Area, Offset, Type, Scale, Invalid, Enum, Bits, Min, Access, Comment
+# 注释行被忽略
+volt, ir, 0x10, uint16, 0.1, 0xffff;0x8000, , , , , 未知的列
+mode, hr, 20, , , , 0=auto;1=manual, , 0, rw,
+state, ir, 21, , , , , alarm=0;fault=15, , ,
+`
+	p, err := Parse([]byte(src), "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	volt, ok := p.Point("volt")
+	if !ok || volt.area != global.AreaInputRegister || volt.Offset != 0x10 || volt.Scale != 0.1 ||
+		!reflect.DeepEqual(volt.Invalid, []uint64{0xffff, 0x8000}) {
+		t.Errorf("volt = %+v", volt)
+	}
+	mode, ok := p.Point("mode")
+	if !ok || mode.typ != mbcodec.Uint16 || mode.Min == nil || *mode.Min != 0 ||
+		!reflect.DeepEqual(mode.Enum, map[int64]string{0: "auto", 1: "manual"}) {
+		t.Errorf("mode = %+v", mode)
+	}
+	state, ok := p.Point("state")
+	if !ok || !reflect.DeepEqual(state.Bits, map[string]uint{"alarm": 0, "fault": 15}) {
+		t.Errorf("state = %+v", state)
+	}
+}
+
+func TestParseCSVInvalid(t *testing.T) {
+	tests := map[string]string{
+		"empty":   "",
+		"offset":  "name,area,offset\na,hr,65536\n",
+		"scale":   "name,area,scale\na,hr,x\n",
+		"invalid": "name,area,invalid\na,hr,0xffff;z\n",
+		"enum":    "name,area,enum\na,hr,0=auto;manual\n",
+		"bits":    "name,area,bits\na,hr,alarm=64\n",
+		"columns": "name,area\na,hr,1\n",
+		"point":   "name,area\na,xr\n",
+	}
+	for name, src := range tests {
+		if _, err := Parse([]byte(src), "csv"); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package profile
+
+import (
+	"fmt"
+	"sync"
+
+	"ckklearn.com/testmodbus/mbrtu"
+	"ckklearn.com/testmodbus/mbrtu/mbcodec"
+)
+
+// Device 设备实例
+// 某一设备类型在某个主站上的某个从站
+type Device struct {
+	Name    string
+	Profile *Profile
+	Master  *mbrtu.RtuMaster
+	Slave   byte
+}
+
+// Site 按名称管理设备实例
+type Site struct {
+	l       *sync.RWMutex
+	devices map[string]*Device
+}
+
+// NewSite 构造函数
+func NewSite() *Site {
+	return &Site{
+		l:       new(sync.RWMutex),
+		devices: make(map[string]*Device),
+	}
+}
+
+// AddDevice 添加设备实例
+// `slave` 为0时使用设备类型的默认从站号，设备类型指定了协议变体时为从站配置该变体
+func (s *Site) AddDevice(name string, p *Profile, m *mbrtu.RtuMaster, slave byte) (*Device, error) {
+	if slave == 0 {
+		slave = p.Slave
+	}
+	if slave == 0 {
+		return nil, fmt.Errorf("device %s: no slave address", name)
+	}
+
+	s.l.Lock()
+	defer s.l.Unlock()
+	if _, ok := s.devices[name]; ok {
+		return nil, fmt.Errorf("device %s already exists", name)
+	}
+	if p.dialect != nil {
+		m.SetSlaveDialect(slave, p.dialect)
+	}
+	d := &Device{Name: name, Profile: p, Master: m, Slave: slave}
+	s.devices[name] = d
+	return d, nil
+}
+
+// Device 按名称查找设备实例
+func (s *Site) Device(name string) (*Device, bool) {
+	s.l.RLock()
+	defer s.l.RUnlock()
+	d, ok := s.devices[name]
+	return d, ok
+}
+
+// 按名称查找设备实例和测点
+func (s *Site) lookup(device, point string) (*Device, *Point, error) {
+	d, ok := s.Device(device)
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown device `%s`", device)
+	}
+	pt, ok := d.Profile.Point(point)
+	if !ok {
+		return nil, nil, fmt.Errorf("device %s: unknown point `%s`", device, point)
+	}
+	return d, pt, nil
+}
+
+// ReadPoint 读取设备的测点
+func (s *Site) ReadPoint(device, point string) (mbcodec.Value, error) {
+	d, pt, err := s.lookup(device, point)
+	if err != nil {
+		return mbcodec.Value{}, err
+	}
+	return d.ReadPoint(pt)
+}
+
+// WritePoint 写入设备的测点
+// 数值测点的`value`为工程值，字符串测点为string
+func (s *Site) WritePoint(device, point string, value interface{}) error {
+	d, pt, err := s.lookup(device, point)
+	if err != nil {
+		return err
+	}
+	return d.WritePoint(pt, value)
+}
+
+// ReadPoint 读取测点
+// 字符串测点的`Raw`为string
+func (d *Device) ReadPoint(pt *Point) (mbcodec.Value, error) {
+	if !pt.Readable() {
+		return mbcodec.Value{}, fmt.Errorf("point %s is write-only", pt.Name)
+	}
+	crcOrder := d.Profile.crcOrder
+	num := pt.Registers()
+
+	p, err := d.Master.ReadAreaBytes(d.Slave, pt.area, pt.Offset, num, crcOrder)
+	if err != nil {
+		return mbcodec.Value{}, fmt.Errorf("point %s: %v", pt.Name, err)
+	}
+	if pt.area.IsBit() {
+		bits, err := mbcodec.Bools(p, 1)
+		if err != nil {
+			return mbcodec.Value{}, err
+		}
+		v := mbcodec.Value{Raw: bits[0], Valid: true}
+		if bits[0] {
+			v.Bits, v.Float = 1, 1
+		}
+		return v, nil
+	}
+
+	if pt.str != nil {
+		str, err := mbcodec.String(p, *pt.str)
+		if err != nil {
+			return mbcodec.Value{}, err
+		}
+		return mbcodec.Value{Raw: str, Valid: true}, nil
+	}
+	return pt.conv.Convert(p, pt.typ, pt.order)
+}
+
+// WritePoint 写入测点
+// 数值测点的`value`为工程值，字符串测点为string
+func (d *Device) WritePoint(pt *Point, value interface{}) error {
+	if !pt.Writable() {
+		return fmt.Errorf("point %s is read-only", pt.Name)
+	}
+	crcOrder := d.Profile.crcOrder
+
+	if pt.str != nil {
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("point %s: expected string, got %T", pt.Name, value)
+		}
+		regs, err := mbcodec.EncodeString(s, int(pt.Length), *pt.str)
+		if err != nil {
+			return err
+		}
+		return d.Master.WriteMultiRegisters(d.Slave, pt.Offset, regs, crcOrder)
+	}
+
+	f, ok := mbcodec.ToFloat64(value)
+	if !ok {
+		return fmt.Errorf("point %s: expected number, got %T", pt.Name, value)
+	}
+	if (pt.Min != nil && f < *pt.Min) || (pt.Max != nil && f > *pt.Max) {
+		return fmt.Errorf("point %s: value %v out of range", pt.Name, value)
+	}
+	if pt.area.IsBit() {
+		return d.Master.WriteSingleCoil(d.Slave, pt.Offset, f != 0, crcOrder)
+	}
+
+	var regs []uint16
+	var err error
+	if pt.conv.IsIdentity() {
+		regs, err = mbcodec.Encode(value, pt.typ, pt.order)
+	} else {
+		regs, err = pt.conv.EncodeValue(f, pt.typ, pt.order)
+	}
+	if err != nil {
+		return err
+	}
+	if len(regs) == 1 {
+		return d.Master.WriteSingleRegister(d.Slave, pt.Offset, regs[0], crcOrder)
+	}
+	return d.Master.WriteMultiRegisters(d.Slave, pt.Offset, regs, crcOrder)
+}
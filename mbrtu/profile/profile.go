@@ -0,0 +1,369 @@
+// Package profile 设备类型描述文件
+// 用json、yaml或csv描述设备的串口参数、从站默认值和测点，运行时按名称读写测点
+package profile
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tarm/serial"
+	"gopkg.in/yaml.v2"
+
+	"ckklearn.com/testmodbus/global"
+	"ckklearn.com/testmodbus/mbrtu"
+	"ckklearn.com/testmodbus/mbrtu/mbcodec"
+)
+
+// 字符串类型的测点
+const typeString = "string"
+
+// Profile 设备类型
+type Profile struct {
+	Name     string  `json:"name" yaml:"name"`
+	Slave    byte    `json:"slave" yaml:"slave"`         // 默认从站号
+	Serial   *Serial `json:"serial" yaml:"serial"`       // 默认串口参数
+	CrcOrder string  `json:"crc_order" yaml:"crc_order"` // crc字节序：little（<）、big（>）或auto，默认为little
+	Dialect  string  `json:"dialect" yaml:"dialect"`     // 协议变体的名称，为空时使用标准mbrtu
+	Points   []Point `json:"points" yaml:"points"`
+
+	crcOrder binary.ByteOrder
+	dialect  *mbrtu.Dialect
+	points   map[string]*Point
+}
+
+// Serial 串口参数
+type Serial struct {
+	Port     string  `json:"port" yaml:"port"`
+	Baud     int     `json:"baud" yaml:"baud"`
+	DataBits byte    `json:"data_bits" yaml:"data_bits"` // 默认为8
+	Parity   string  `json:"parity" yaml:"parity"`       // n、o或e，默认为n
+	StopBits float64 `json:"stop_bits" yaml:"stop_bits"` // 1、1.5或2，默认为1
+	Timeout  int     `json:"timeout" yaml:"timeout"`     // 读取超时（毫秒）
+}
+
+// Point 测点
+type Point struct {
+	Name        string           `json:"name" yaml:"name"`
+	Description string           `json:"description" yaml:"description"`
+	Address     string           `json:"address" yaml:"address"` // 地址，如40101、%MW100，指定时忽略数据区和偏移量
+	Area        string           `json:"area" yaml:"area"`       // 数据区：coil、di、ir或hr
+	Offset      uint16           `json:"offset" yaml:"offset"`   // 从0开始的偏移量
+	Type        string           `json:"type" yaml:"type"`       // 数值类型或string，默认为bool（线圈和离散输入）或uint16
+	Order       string           `json:"order" yaml:"order"`     // 多寄存器数值的排列顺序，默认为ABCD
+	Length      uint16           `json:"length" yaml:"length"`   // 字符串占用的寄存器个数
+	Charset     string           `json:"charset" yaml:"charset"` // 字符串的字符集，默认为ascii
+	SwapBytes   bool             `json:"swap_bytes" yaml:"swap_bytes"`
+	Scale       float64          `json:"scale" yaml:"scale"` // 增益，为0时不缩放
+	Bias        float64          `json:"bias" yaml:"bias"`   // 偏移
+	BCD         bool             `json:"bcd" yaml:"bcd"`
+	Invalid     []uint64         `json:"invalid" yaml:"invalid"` // 表示不可用的原始值
+	Enum        map[int64]string `json:"enum" yaml:"enum"`
+	Bits        map[string]uint  `json:"bits" yaml:"bits"`
+	Unit        string           `json:"unit" yaml:"unit"`
+	Min         *float64         `json:"min" yaml:"min"`       // 工程值下限，写入时检查
+	Max         *float64         `json:"max" yaml:"max"`       // 工程值上限，写入时检查
+	Access      string           `json:"access" yaml:"access"` // r、w或rw，默认为线圈和保持寄存器rw，其余r
+
+	area  global.Area
+	typ   mbcodec.Type
+	order mbcodec.Order
+	conv  *mbcodec.Converter
+	str   *mbcodec.StringOptions
+}
+
+// Load 从文件加载设备类型
+// 按扩展名区分json、yaml和csv，未指定名称时使用文件名
+func Load(path string) (*Profile, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ext := filepath.Ext(path)
+	p, err := decode(data, strings.TrimPrefix(ext, "."))
+	if err != nil {
+		return nil, err
+	}
+	if p.Name == "" {
+		p.Name = strings.TrimSuffix(filepath.Base(path), ext)
+	}
+	err = p.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Parse 解析设备类型
+// `format` 为json、yaml、yml或csv，csv只包含测点表
+func Parse(data []byte, format string) (*Profile, error) {
+	p, err := decode(data, format)
+	if err != nil {
+		return nil, err
+	}
+	err = p.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// 按格式解码设备类型，不做检查
+func decode(data []byte, format string) (*Profile, error) {
+	p := new(Profile)
+	var err error
+	switch strings.ToLower(format) {
+	case "json":
+		err = json.Unmarshal(data, p)
+	case "yaml", "yml":
+		err = yaml.Unmarshal(data, p)
+	case "csv":
+		p.Points, err = parseCSV(data)
+	default:
+		return nil, fmt.Errorf("unsupported profile format `%s`", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Validate 检查设备类型并解析其中的名称
+// 手动构造的设备类型需要先调用这个函数
+func (p *Profile) Validate() error {
+	switch strings.ToLower(p.CrcOrder) {
+	case "", "little", "<":
+		p.crcOrder = binary.LittleEndian
+	case "big", ">":
+		p.crcOrder = binary.BigEndian
+	case "auto":
+		p.crcOrder = mbrtu.AutoCrcOrder
+	default:
+		return fmt.Errorf("profile %s: invalid crc order `%s`", p.Name, p.CrcOrder)
+	}
+
+	if p.Dialect != "" {
+		d, ok := mbrtu.LookupDialect(p.Dialect)
+		if !ok {
+			return fmt.Errorf("profile %s: unknown dialect `%s`", p.Name, p.Dialect)
+		}
+		p.dialect = d
+	}
+
+	p.points = make(map[string]*Point, len(p.Points))
+	for i := range p.Points {
+		pt := &p.Points[i]
+		if _, ok := p.points[pt.Name]; ok {
+			return fmt.Errorf("profile %s: duplicate point `%s`", p.Name, pt.Name)
+		}
+		err := pt.compile()
+		if err != nil {
+			return fmt.Errorf("profile %s: point %s: %v", p.Name, pt.Name, err)
+		}
+		p.points[pt.Name] = pt
+	}
+	return nil
+}
+
+// Point 按名称查找测点
+func (p *Profile) Point(name string) (*Point, bool) {
+	pt, ok := p.points[name]
+	return pt, ok
+}
+
+// CrcOrderValue 设备类型的crc字节序
+func (p *Profile) CrcOrderValue() binary.ByteOrder {
+	return p.crcOrder
+}
+
+// SerialConfig 按串口参数生成串口配置
+func (p *Profile) SerialConfig() (*serial.Config, error) {
+	s := p.Serial
+	if s == nil {
+		return nil, fmt.Errorf("profile %s: no serial parameters", p.Name)
+	}
+
+	c := &serial.Config{
+		Name:        s.Port,
+		Baud:        s.Baud,
+		Size:        s.DataBits,
+		ReadTimeout: time.Duration(s.Timeout) * time.Millisecond,
+	}
+	if c.Size == 0 {
+		c.Size = 8
+	}
+	switch strings.ToLower(s.Parity) {
+	case "", "n":
+		c.Parity = serial.ParityNone
+	case "o":
+		c.Parity = serial.ParityOdd
+	case "e":
+		c.Parity = serial.ParityEven
+	default:
+		return nil, fmt.Errorf("profile %s: invalid parity `%s`", p.Name, s.Parity)
+	}
+	switch s.StopBits {
+	case 0, 1:
+		c.StopBits = serial.Stop1
+	case 1.5:
+		c.StopBits = serial.Stop1Half
+	case 2:
+		c.StopBits = serial.Stop2
+	default:
+		return nil, fmt.Errorf("profile %s: invalid stop bits %v", p.Name, s.StopBits)
+	}
+	return c, nil
+}
+
+// OpenMaster 按串口参数打开主站
+// 设备类型指定了协议变体时，将其作为主站默认的协议变体
+func (p *Profile) OpenMaster() (*mbrtu.RtuMaster, error) {
+	c, err := p.SerialConfig()
+	if err != nil {
+		return nil, err
+	}
+	m, err := mbrtu.NewRtuMaster(c)
+	if err != nil {
+		return nil, err
+	}
+	if p.dialect != nil {
+		m.SetDialect(p.dialect)
+	}
+	return m, nil
+}
+
+// 解析测点中的名称
+func (pt *Point) compile() error {
+	if pt.Name == "" {
+		return fmt.Errorf("point requires a name")
+	}
+	if pt.Address != "" {
+		a, err := global.ParseAddress(pt.Address)
+		if err != nil {
+			return err
+		}
+		pt.Area, pt.Offset = a.Area.String(), a.Offset
+	}
+	area, err := global.ParseArea(pt.Area)
+	if err != nil {
+		return err
+	}
+	pt.area = area
+
+	switch {
+	case strings.ToLower(pt.Type) == typeString:
+		if area.IsBit() {
+			return fmt.Errorf("area %s does not support strings", area)
+		}
+		if pt.Length == 0 {
+			return fmt.Errorf("string point requires length")
+		}
+		cs, err := mbcodec.ParseCharset(pt.Charset)
+		if pt.Charset == "" {
+			cs, err = mbcodec.ASCII, nil
+		}
+		if err != nil {
+			return err
+		}
+		pt.str = &mbcodec.StringOptions{Charset: cs, SwapBytes: pt.SwapBytes}
+	case pt.Type == "":
+		pt.typ = mbcodec.Uint16
+		if area.IsBit() {
+			pt.typ = mbcodec.Bool
+		}
+	default:
+		pt.typ, err = mbcodec.ParseType(pt.Type)
+		if err != nil {
+			return err
+		}
+	}
+	if area.IsBit() && pt.typ != mbcodec.Bool {
+		return fmt.Errorf("area %s only supports bool", area)
+	}
+
+	if pt.Order != "" {
+		pt.order, err = mbcodec.ParseOrder(pt.Order)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch pt.Access {
+	case "":
+		pt.Access = "r"
+		if area.Writable() {
+			pt.Access = "rw"
+		}
+	case "r", "w", "rw":
+		if strings.Contains(pt.Access, "w") && !area.Writable() {
+			return fmt.Errorf("area %s is read-only", area)
+		}
+	default:
+		return fmt.Errorf("invalid access `%s`", pt.Access)
+	}
+
+	pt.conv = &mbcodec.Converter{
+		BCD:     pt.BCD,
+		Gain:    pt.Scale,
+		Offset:  pt.Bias,
+		Invalid: pt.Invalid,
+		Enum:    pt.Enum,
+		Bits:    pt.Bits,
+	}
+	return nil
+}
+
+// AreaValue 测点的数据区
+func (pt *Point) AreaValue() global.Area {
+	return pt.area
+}
+
+// TypeValue 测点的数值类型
+// 字符串测点返回空
+func (pt *Point) TypeValue() mbcodec.Type {
+	return pt.typ
+}
+
+// OrderValue 测点的排列顺序
+func (pt *Point) OrderValue() mbcodec.Order {
+	return pt.order
+}
+
+// StringOptions 字符串测点的编码选项
+// 不是字符串测点时返回false
+func (pt *Point) StringOptions() (mbcodec.StringOptions, bool) {
+	if pt.str == nil {
+		return mbcodec.StringOptions{}, false
+	}
+	return *pt.str, true
+}
+
+// Converter 测点的工程转换
+func (pt *Point) Converter() *mbcodec.Converter {
+	return pt.conv
+}
+
+// Registers 测点占用的寄存器或位的个数
+func (pt *Point) Registers() uint16 {
+	if pt.area.IsBit() {
+		return 1
+	}
+	if pt.str != nil {
+		return pt.Length
+	}
+	return uint16(pt.typ.Registers())
+}
+
+// Readable 测点是否可读
+func (pt *Point) Readable() bool {
+	return strings.Contains(pt.Access, "r")
+}
+
+// Writable 测点是否可写
+func (pt *Point) Writable() bool {
+	return strings.Contains(pt.Access, "w")
+}
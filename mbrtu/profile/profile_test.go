@@ -0,0 +1,198 @@
+package profile
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"ckklearn.com/testmodbus/global"
+	"ckklearn.com/testmodbus/mbrtu"
+	"ckklearn.com/testmodbus/mbrtu/mbcodec"
+)
+
+const meterJSON = `{
+	"name": "meter",
+	"slave": 3,
+	"crc_order": "auto",
+	"dialect": "nr",
+	"points": [
+		{"name": "run", "area": "coil", "offset": 0},
+		{"name": "volt", "area": "ir", "offset": 10, "type": "float32", "order": "CDAB", "unit": "V"},
+		{"name": "model", "area": "hr", "offset": 100, "type": "string", "length": 8, "charset": "gbk"},
+		{"name": "mode", "area": "hr", "offset": 20, "access": "w", "enum": {"0": "auto", "1": "manual"}}
+	]
+}`
+
+const meterYAML = `
+name: meter
+slave: 3
+crc_order: auto
+dialect: nr
+points:
+- {name: run, area: coil, offset: 0}
+- {name: volt, area: ir, offset: 10, type: float32, order: CDAB, unit: V}
+- {name: model, area: hr, offset: 100, type: string, length: 8, charset: gbk}
+- {name: mode, area: hr, offset: 20, access: w, enum: {0: auto, 1: manual}}
+`
+
+func TestParse(t *testing.T) {
+	for format, src := range map[string]string{"json": meterJSON, "yaml": meterYAML} {
+		p, err := Parse([]byte(src), format)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if p.Name != "meter" || p.Slave != 3 || p.CrcOrderValue() != mbrtu.AutoCrcOrder || p.dialect != mbrtu.NRDialect {
+			t.Errorf("%s: profile = %s slave %d crc %v dialect %v", format, p.Name, p.Slave, p.CrcOrderValue(), p.dialect)
+		}
+		if len(p.Points) != 4 {
+			t.Fatalf("%s: %d points, want 4", format, len(p.Points))
+		}
+
+		tests := []struct {
+			name   string
+			area   global.Area
+			typ    mbcodec.Type
+			num    uint16
+			access string
+		}{
+			{"run", global.AreaCoil, mbcodec.Bool, 1, "rw"},
+			{"volt", global.AreaInputRegister, mbcodec.Float32, 2, "r"},
+			{"model", global.AreaHoldingRegister, "", 8, "rw"},
+			{"mode", global.AreaHoldingRegister, mbcodec.Uint16, 1, "w"},
+		}
+		for _, tt := range tests {
+			pt, ok := p.Point(tt.name)
+			if !ok {
+				t.Errorf("%s: point %s not found", format, tt.name)
+				continue
+			}
+			if pt.area != tt.area || pt.typ != tt.typ || pt.Registers() != tt.num || pt.Access != tt.access {
+				t.Errorf("%s: point %s = %s %q %d %s, want %s %q %d %s", format, tt.name,
+					pt.area, pt.typ, pt.Registers(), pt.Access, tt.area, tt.typ, tt.num, tt.access)
+			}
+		}
+
+		volt, _ := p.Point("volt")
+		if volt.order != mbcodec.CDAB || volt.Unit != "V" {
+			t.Errorf("%s: volt order %s unit %s", format, volt.order, volt.Unit)
+		}
+		model, _ := p.Point("model")
+		if model.str == nil || model.str.Charset != mbcodec.GBK {
+			t.Errorf("%s: model string options = %+v", format, model.str)
+		}
+		mode, _ := p.Point("mode")
+		if mode.Readable() || !mode.Writable() || mode.conv.Enum[1] != "manual" {
+			t.Errorf("%s: mode readable %t writable %t enum %v", format, mode.Readable(), mode.Writable(), mode.conv.Enum)
+		}
+		if _, ok := p.Point("missing"); ok {
+			t.Errorf("%s: unknown point found", format)
+		}
+	}
+}
+
+func TestParseDefaults(t *testing.T) {
+	p, err := Parse([]byte(`{"points": [{"name": "a", "area": "di"}, {"name": "b", "area": "ir"}]}`), "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.CrcOrderValue() != binary.LittleEndian || p.dialect != nil {
+		t.Errorf("crc order %v dialect %v, want little endian and none", p.CrcOrderValue(), p.dialect)
+	}
+	a, _ := p.Point("a")
+	b, _ := p.Point("b")
+	if a.typ != mbcodec.Bool || a.Access != "r" || b.typ != mbcodec.Uint16 || b.Access != "r" || b.order != mbcodec.ABCD {
+		t.Errorf("defaults = %q %s, %q %s %s", a.typ, a.Access, b.typ, b.Access, b.order)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		src    string
+	}{
+		{"format", "xml", `<profile/>`},
+		{"json syntax", "json", `{"points": [`},
+		{"yaml syntax", "yaml", "points: [\n"},
+		{"crc order", "yaml", "crc_order: middle"},
+		{"dialect", "yaml", "dialect: unknown"},
+		{"no name", "yaml", "points: [{area: hr}]"},
+		{"duplicate", "yaml", "points: [{name: a, area: hr}, {name: a, area: ir}]"},
+		{"area", "yaml", "points: [{name: a, area: xr}]"},
+		{"type", "yaml", "points: [{name: a, area: hr, type: int128}]"},
+		{"bit type", "yaml", "points: [{name: a, area: coil, type: uint16}]"},
+		{"bit string", "yaml", "points: [{name: a, area: di, type: string, length: 2}]"},
+		{"string length", "yaml", "points: [{name: a, area: hr, type: string}]"},
+		{"charset", "yaml", "points: [{name: a, area: hr, type: string, length: 2, charset: latin1}]"},
+		{"order", "yaml", "points: [{name: a, area: hr, type: uint32, order: ACBD}]"},
+		{"read-only", "yaml", "points: [{name: a, area: ir, access: rw}]"},
+		{"access", "yaml", "points: [{name: a, area: hr, access: x}]"},
+	}
+	for _, tt := range tests {
+		if _, err := Parse([]byte(tt.src), tt.format); err == nil {
+			t.Errorf("%s: want error", tt.name)
+		}
+	}
+}
+
+func TestSerialConfig(t *testing.T) {
+	p, err := Parse([]byte("serial: {port: COM3, baud: 9600, parity: e, stop_bits: 2, timeout: 500}"), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := p.SerialConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "COM3" || c.Baud != 9600 || c.Size != 8 || c.Parity != 'E' || c.StopBits != 2 || c.ReadTimeout.Milliseconds() != 500 {
+		t.Errorf("SerialConfig = %+v", c)
+	}
+
+	for _, src := range []string{"name: x", "serial: {parity: m}", "serial: {stop_bits: 3}"} {
+		p, err := Parse([]byte(src), "yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := p.SerialConfig(); err == nil {
+			t.Errorf("SerialConfig(%s), want error", src)
+		}
+	}
+}
+
+func TestPointAddress(t *testing.T) {
+	p, err := Parse([]byte(`
+points:
+- {name: volt, address: "30101", area: hr, offset: 7}
+- {name: mode, address: "%MW20"}
+- {name: run, address: "00001"}
+`), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		area   global.Area
+		offset uint16
+	}{
+		{"volt", global.AreaInputRegister, 100},
+		{"mode", global.AreaHoldingRegister, 20},
+		{"run", global.AreaCoil, 0},
+	}
+	for _, tt := range tests {
+		pt, _ := p.Point(tt.name)
+		if pt.area != tt.area || pt.Offset != tt.offset {
+			t.Errorf("%s = %s %d, want %s %d", tt.name, pt.area, pt.Offset, tt.area, tt.offset)
+		}
+	}
+
+	csv, err := Parse([]byte("name,address,type\nenergy,400201,uint32\n"), "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pt, _ := csv.Point("energy"); pt.area != global.AreaHoldingRegister || pt.Offset != 200 {
+		t.Errorf("csv energy = %s %d", pt.area, pt.Offset)
+	}
+
+	if _, err := Parse([]byte(`points: [{name: a, address: "50001"}]`), "yaml"); err == nil {
+		t.Error("invalid address, want error")
+	}
+}
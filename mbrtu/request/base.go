@@ -12,7 +12,29 @@ type base struct {
 	offset uint16         // 偏移量
 }
 
+// Addr 请求的从站号
+func (b base) Addr() byte {
+	return b.addr
+}
+
 // FunCode 请求的功能码
 func (b base) FunCode() global.FunCode {
 	return b.fun
 }
+
+// 仅包含从站号和功能码的请求
+// 用于没有数据字段的请求，方便序列化
+type head struct {
+	addr byte           // 从站号
+	fun  global.FunCode // 功能码
+}
+
+// Addr 请求的从站号
+func (h head) Addr() byte {
+	return h.addr
+}
+
+// FunCode 请求的功能码
+func (h head) FunCode() global.FunCode {
+	return h.fun
+}
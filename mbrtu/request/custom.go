@@ -0,0 +1,119 @@
+// 用户自定义功能码的注册表和请求结构
+
+package request
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"ckklearn.com/testmodbus/global"
+	"ckklearn.com/testmodbus/mbrtu/mbcrc"
+)
+
+// CustomFunCode 用户自定义功能码的编解码函数
+type CustomFunCode struct {
+	// Encode 将调用参数编码为功能码之后、crc之前的请求数据
+	Encode func(args interface{}) ([]byte, error)
+
+	// ResponseLen 根据已读取的返回报文计算完整的返回报文字节长度
+	// 传入的报文至少包含5个字节
+	ResponseLen func(res []byte) int
+
+	// Decode 解析返回报文中功能码之后、crc之前的数据并写入`dst`
+	// 为nil时原样写入
+	Decode func(dst, data []byte) (int, error)
+}
+
+var (
+	customFunCodes = make(map[global.FunCode]CustomFunCode)
+	customLock     = new(sync.RWMutex)
+)
+
+// IsUserDefinedFunCode 判断功能码是否在用户自定义范围内
+// 用户自定义功能码为0x41-0x48和0x64-0x6e
+func IsUserDefinedFunCode(fun global.FunCode) bool {
+	return (fun >= 0x41 && fun <= 0x48) || (fun >= 0x64 && fun <= 0x6e)
+}
+
+// RegisterFunCode 注册用户自定义功能码
+// 重复注册会覆盖之前的编解码函数
+func RegisterFunCode(fun global.FunCode, c CustomFunCode) error {
+	if !IsUserDefinedFunCode(fun) {
+		return fmt.Errorf("function code `%x` is not user defined", fun)
+	}
+	if c.Encode == nil || c.ResponseLen == nil {
+		return fmt.Errorf("function code `%x` requires Encode and ResponseLen", fun)
+	}
+
+	customLock.Lock()
+	defer customLock.Unlock()
+	customFunCodes[fun] = c
+	return nil
+}
+
+// UnregisterFunCode 注销用户自定义功能码
+func UnregisterFunCode(fun global.FunCode) {
+	customLock.Lock()
+	defer customLock.Unlock()
+	delete(customFunCodes, fun)
+}
+
+// LookupFunCode 查找已注册的用户自定义功能码
+func LookupFunCode(fun global.FunCode) (CustomFunCode, bool) {
+	customLock.RLock()
+	defer customLock.RUnlock()
+	c, ok := customFunCodes[fun]
+	return c, ok
+}
+
+// RtuCustomRequest 用户自定义功能码请求
+type RtuCustomRequest struct {
+	head
+	spec CustomFunCode
+	data []byte
+}
+
+// NewRtuCustomRequest 构造函数
+// 功能码需要先通过`RegisterFunCode`注册
+func NewRtuCustomRequest(addr byte, fun global.FunCode, args interface{}) (*RtuCustomRequest, error) {
+	c, ok := LookupFunCode(fun)
+	if !ok {
+		return nil, fmt.Errorf("function code `%x` is not registered", fun)
+	}
+	data, err := c.Encode(args)
+	if err != nil {
+		return nil, err
+	}
+	return &RtuCustomRequest{
+		head: head{
+			addr: addr,
+			fun:  fun,
+		},
+		spec: c,
+		data: data,
+	}, nil
+}
+
+// Serialize 将结构序列化为rtu请求报文
+func (r *RtuCustomRequest) Serialize(buf *bytes.Buffer, crcOrder binary.ByteOrder) error {
+	err := binary.Write(buf, binary.BigEndian, r.head)
+	if err != nil {
+		return err
+	}
+	buf.Write(r.data)
+	crc16 := mbcrc.Crc16(buf.Bytes())
+	return binary.Write(buf, crcOrder, crc16)
+}
+
+// ExpectedLen 期望的返回报文字节长度
+// 完整长度由`ResponseLen`计算，这里返回最小长度
+func (r *RtuCustomRequest) ExpectedLen() int {
+	return 5
+}
+
+// ResponseLen 根据返回报文计算完整长度
+func (r *RtuCustomRequest) ResponseLen(res []byte) int {
+	return r.spec.ResponseLen(res)
+}
@@ -0,0 +1,56 @@
+package request
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"ckklearn.com/testmodbus/global"
+	"ckklearn.com/testmodbus/mbrtu/mbcrc"
+)
+
+// RtuDiagnosticsRequest 诊断请求
+// 子功能码在报文中与偏移量位置相同，因此复用`base`中的偏移量字段
+type RtuDiagnosticsRequest struct {
+	base
+	data []uint16
+}
+
+// NewRtuDiagnosticsRequest 构造函数
+func NewRtuDiagnosticsRequest(addr byte, sub global.DiagSubFunCode, data []uint16) *RtuDiagnosticsRequest {
+	return &RtuDiagnosticsRequest{
+		base: base{
+			addr:   addr,
+			fun:    global.Diagnostics,
+			offset: sub,
+		},
+		data: data,
+	}
+}
+
+// SubFunCode 请求的子功能码
+func (r *RtuDiagnosticsRequest) SubFunCode() global.DiagSubFunCode {
+	return r.offset
+}
+
+// Serialize 将结构序列化为rtu请求报文
+func (r *RtuDiagnosticsRequest) Serialize(buf *bytes.Buffer, crcOrder binary.ByteOrder) error {
+	err := binary.Write(buf, binary.BigEndian, r.base)
+	if err != nil {
+		return err
+	}
+	err = binary.Write(buf, binary.BigEndian, r.data)
+	if err != nil {
+		return err
+	}
+	crc16 := mbcrc.Crc16(buf.Bytes())
+	return binary.Write(buf, crcOrder, crc16)
+}
+
+// ExpectedLen 期望的返回报文字节长度
+// 强制只听模式下从站不返回报文，其余子功能返回与请求等长的报文
+func (r *RtuDiagnosticsRequest) ExpectedLen() int {
+	if r.offset == global.DiagForceListenOnlyMode {
+		return 0
+	}
+	return len(r.data)*2 + 6
+}
@@ -0,0 +1,84 @@
+// Enron（Daniel）modbus的rtu请求结构
+// 部分地址范围内的寄存器为32位，每个寄存器返回4个字节
+
+package request
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"ckklearn.com/testmodbus/global"
+)
+
+// MaxEnronReadNum 单个请求最多读取的32位寄存器个数
+// 返回报文的字节数字段只有1个字节，每个寄存器占4个字节
+const MaxEnronReadNum uint16 = 63
+
+// EnronRange Enron模式下32位寄存器的地址范围
+// 包含`Start`和`End`，地址即报文中的偏移量
+type EnronRange struct {
+	Start uint16
+	End   uint16
+}
+
+// EnronRanges 32位寄存器的地址范围列表
+type EnronRanges []EnronRange
+
+// DefaultEnronRanges 常用的32位寄存器地址范围
+// 5001-5999为32位浮点数，7001-7999为32位整数
+var DefaultEnronRanges = EnronRanges{
+	{Start: 5001, End: 5999},
+	{Start: 7001, End: 7999},
+}
+
+// Contains 判断偏移量是否在32位寄存器地址范围内
+func (rs EnronRanges) Contains(offset uint16) bool {
+	for _, r := range rs {
+		if offset >= r.Start && offset <= r.End {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainsRange 判断从`offset`开始的`num`个寄存器是否都在同一个32位寄存器地址范围内
+func (rs EnronRanges) ContainsRange(offset, num uint16) bool {
+	if num == 0 {
+		return rs.Contains(offset)
+	}
+	end := int(offset) + int(num) - 1
+	for _, r := range rs {
+		if offset >= r.Start && end <= int(r.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// RtuEnronReadRequest 读32位寄存器请求
+// 请求报文与标准读请求相同，返回的每个寄存器占4个字节
+type RtuEnronReadRequest struct {
+	RtuReadRequest
+}
+
+// NewRtuEnronReadRequest 构造函数
+func NewRtuEnronReadRequest(addr byte, fun global.FunCode, offset, num uint16) *RtuEnronReadRequest {
+	return &RtuEnronReadRequest{
+		RtuReadRequest: *NewRtuReadRequest(addr, fun, offset, num),
+	}
+}
+
+// ExpectedLen 期望的返回报文字节长度
+func (r *RtuEnronReadRequest) ExpectedLen() int {
+	return int(r.num)*4 + 5
+}
+
+// Serialize 将结构序列化为rtu请求报文
+// 读取的个数超过`MaxEnronReadNum`时返回报文的字节数会溢出，返回错误
+func (r *RtuEnronReadRequest) Serialize(buf *bytes.Buffer, crcOrder binary.ByteOrder) error {
+	if r.num == 0 || r.num > MaxEnronReadNum {
+		return fmt.Errorf("enron read num must be 1~%d, got %d", MaxEnronReadNum, r.num)
+	}
+	return r.RtuReadRequest.Serialize(buf, crcOrder)
+}
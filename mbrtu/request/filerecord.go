@@ -0,0 +1,175 @@
+package request
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"ckklearn.com/testmodbus/global"
+	"ckklearn.com/testmodbus/mbrtu/mbcrc"
+)
+
+const (
+	// FileRefType 文件记录的引用类型，固定为6
+	FileRefType byte = 0x06
+	// 文件记录请求和返回的数据最大字节长度
+	maxFileDataSize int = 0xf5
+	// MaxFileRecordNum 最大的文件记录号
+	MaxFileRecordNum uint16 = 0x270f
+)
+
+// FileRecord 文件记录子请求
+// 每个子请求访问一个文件中连续的若干记录，每个记录为2个字节
+type FileRecord struct {
+	File   uint16   // 文件号
+	Record uint16   // 起始记录号
+	Length uint16   // 记录个数，写请求时以`Data`的长度为准
+	Data   []uint16 // 记录数据
+}
+
+// 检查文件记录号是否越界
+func checkFileRecord(fr FileRecord, length int) error {
+	if fr.File == 0 {
+		return fmt.Errorf("invalid file number 0")
+	}
+	if int(fr.Record)+length-1 > int(MaxFileRecordNum) {
+		return fmt.Errorf("record %d with length %d exceeds max record number", fr.Record, length)
+	}
+	return nil
+}
+
+// ---- 读文件记录 ----
+
+// RtuReadFileRecordRequest 读文件记录请求
+// 一个请求可以包含多个子请求
+type RtuReadFileRecordRequest struct {
+	head
+	records []FileRecord
+}
+
+// NewRtuReadFileRecordRequest 构造函数
+func NewRtuReadFileRecordRequest(addr byte, records []FileRecord) *RtuReadFileRecordRequest {
+	return &RtuReadFileRecordRequest{
+		head: head{
+			addr: addr,
+			fun:  global.ReadFileRecord,
+		},
+		records: records,
+	}
+}
+
+// Serialize 将结构序列化为rtu请求报文
+func (r *RtuReadFileRecordRequest) Serialize(buf *bytes.Buffer, crcOrder binary.ByteOrder) error {
+	dataSize := len(r.records) * 7
+	if dataSize == 0 || dataSize > maxFileDataSize {
+		return fmt.Errorf("invalid read file record request size %d", dataSize)
+	}
+	if r.ExpectedLen()-5 > maxFileDataSize {
+		return fmt.Errorf("read file record response too long")
+	}
+
+	err := binary.Write(buf, binary.BigEndian, r.head)
+	if err != nil {
+		return err
+	}
+	buf.WriteByte(byte(dataSize))
+	for _, fr := range r.records {
+		err = checkFileRecord(fr, int(fr.Length))
+		if err != nil {
+			return err
+		}
+		buf.WriteByte(FileRefType)
+		err = binary.Write(buf, binary.BigEndian, []uint16{fr.File, fr.Record, fr.Length})
+		if err != nil {
+			return err
+		}
+	}
+	crc16 := mbcrc.Crc16(buf.Bytes())
+	return binary.Write(buf, crcOrder, crc16)
+}
+
+// ExpectedLen 期望的返回报文字节长度
+// 每个子请求返回1个字节长度、1个字节引用类型和记录数据
+func (r *RtuReadFileRecordRequest) ExpectedLen() int {
+	dataLen := 0
+	for _, fr := range r.records {
+		dataLen += 2 + int(fr.Length)*2
+	}
+	return dataLen + 5
+}
+
+// ---- 写文件记录 ----
+
+// RtuWriteFileRecordRequest 写文件记录请求
+// 一个请求可以包含多个子请求，从站原样返回请求报文
+type RtuWriteFileRecordRequest struct {
+	head
+	records []FileRecord
+}
+
+// NewRtuWriteFileRecordRequest 构造函数
+func NewRtuWriteFileRecordRequest(addr byte, records []FileRecord) *RtuWriteFileRecordRequest {
+	return &RtuWriteFileRecordRequest{
+		head: head{
+			addr: addr,
+			fun:  global.WriteFileRecord,
+		},
+		records: records,
+	}
+}
+
+// Payload 功能码之后、crc之前的请求数据
+// 即从站正常返回时应回显的数据
+func (r *RtuWriteFileRecordRequest) Payload() ([]byte, error) {
+	dataSize := 0
+	for _, fr := range r.records {
+		dataSize += 7 + len(fr.Data)*2
+	}
+	if dataSize == 0 || dataSize > maxFileDataSize {
+		return nil, fmt.Errorf("invalid write file record request size %d", dataSize)
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, dataSize+1))
+	buf.WriteByte(byte(dataSize))
+	for _, fr := range r.records {
+		err := checkFileRecord(fr, len(fr.Data))
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteByte(FileRefType)
+		err = binary.Write(buf, binary.BigEndian, []uint16{fr.File, fr.Record, uint16(len(fr.Data))})
+		if err != nil {
+			return nil, err
+		}
+		err = binary.Write(buf, binary.BigEndian, fr.Data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// Serialize 将结构序列化为rtu请求报文
+func (r *RtuWriteFileRecordRequest) Serialize(buf *bytes.Buffer, crcOrder binary.ByteOrder) error {
+	payload, err := r.Payload()
+	if err != nil {
+		return err
+	}
+	err = binary.Write(buf, binary.BigEndian, r.head)
+	if err != nil {
+		return err
+	}
+	buf.Write(payload)
+	crc16 := mbcrc.Crc16(buf.Bytes())
+	return binary.Write(buf, crcOrder, crc16)
+}
+
+// ExpectedLen 期望的返回报文字节长度
+// 返回报文与请求报文相同
+func (r *RtuWriteFileRecordRequest) ExpectedLen() int {
+	dataSize := 0
+	for _, fr := range r.records {
+		dataSize += 7 + len(fr.Data)*2
+	}
+	return dataSize + 5
+}
@@ -17,5 +17,35 @@ type RtuRequest interface {
 
 	// ExpectedLen 当前请求所期望的返回报文字节长度
 	// 期望的长度为正常返回的长度，异常返回的长度需要自行判断
+	// 返回0表示从站不会返回报文
 	ExpectedLen() int
 }
+
+// RtuAddrRequest 提供从站号的rtu请求
+// 主站按从站号选择协议变体、crc字节序和超时，并跟踪从站的健康状态
+// 未实现这个接口的请求由主站从序列化的报文中获取从站号
+type RtuAddrRequest interface {
+	RtuRequest
+
+	// Addr 请求的从站号
+	Addr() byte
+}
+
+// RtuVarLenRequest 返回报文长度不定的rtu请求
+// 完整长度需要根据已读取的返回报文计算
+type RtuVarLenRequest interface {
+	RtuRequest
+
+	// ResponseLen 根据已读取的返回报文计算完整的返回报文字节长度
+	// `res` 至少包含最小长度的正常返回报文
+	ResponseLen(res []byte) int
+}
+
+// RtuResponseDecoder 自行解析正常返回报文的rtu请求
+// 异常返回仍由主站统一解析
+type RtuResponseDecoder interface {
+	RtuRequest
+
+	// DecodeResponse 解析完整的正常返回报文（含从站号和crc），将数据写入`dst`
+	DecodeResponse(dst, res []byte) (int, error)
+}
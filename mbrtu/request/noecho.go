@@ -0,0 +1,18 @@
+package request
+
+// RtuNoEchoRequest 从站不返回报文的请求
+// 包装任意请求，用于写入后不回显的从站
+type RtuNoEchoRequest struct {
+	RtuRequest
+}
+
+// NewRtuNoEchoRequest 构造函数
+func NewRtuNoEchoRequest(r RtuRequest) *RtuNoEchoRequest {
+	return &RtuNoEchoRequest{RtuRequest: r}
+}
+
+// ExpectedLen 期望的返回报文字节长度
+// 从站不返回报文，固定为0
+func (r *RtuNoEchoRequest) ExpectedLen() int {
+	return 0
+}
@@ -0,0 +1,89 @@
+package request
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"ckklearn.com/testmodbus/global"
+	"ckklearn.com/testmodbus/mbrtu/mbcrc"
+)
+
+// MaxPduLen rtu下pdu（功能码和数据）的最大字节长度
+const MaxPduLen int = 253
+
+// RtuRawRequest 原始pdu请求
+// 返回报文的长度根据报文内容推断，无法推断时读取到帧间隔为止
+type RtuRawRequest struct {
+	addr byte
+	pdu  []byte
+}
+
+// NewRtuRawRequest 构造函数
+// `pdu` 为功能码加数据，不包含从站号和crc
+func NewRtuRawRequest(addr byte, pdu []byte) (*RtuRawRequest, error) {
+	if len(pdu) == 0 || len(pdu) > MaxPduLen {
+		return nil, fmt.Errorf("invalid pdu length %d", len(pdu))
+	}
+	return &RtuRawRequest{addr: addr, pdu: pdu}, nil
+}
+
+// Addr 请求的从站号
+func (r *RtuRawRequest) Addr() byte {
+	return r.addr
+}
+
+// FunCode 请求的功能码
+func (r *RtuRawRequest) FunCode() global.FunCode {
+	return r.pdu[0]
+}
+
+// Serialize 将结构序列化为rtu请求报文
+func (r *RtuRawRequest) Serialize(buf *bytes.Buffer, crcOrder binary.ByteOrder) error {
+	buf.WriteByte(r.addr)
+	buf.Write(r.pdu)
+	crc16 := mbcrc.Crc16(buf.Bytes())
+	return binary.Write(buf, crcOrder, crc16)
+}
+
+// ExpectedLen 期望的返回报文字节长度
+// 广播请求没有返回报文，其余请求的完整长度由`ResponseLen`推断
+func (r *RtuRawRequest) ExpectedLen() int {
+	if r.addr == 0 {
+		return 0
+	}
+	return 5
+}
+
+// ResponseLen 根据返回报文推断完整长度
+// 返回-1表示无法推断，需要读取到帧间隔为止
+func (r *RtuRawRequest) ResponseLen(res []byte) int {
+	switch res[1] {
+	case global.ReadCoils, global.ReadInputs, global.ReadHoldingRegisters, global.ReadInputRegisters,
+		global.GetCommEventLog, global.ReadFileRecord, 0x17:
+		// 0x17为读写多个寄存器，`res[2]` 是数据的字节长度
+		return int(res[2]) + 5
+	case global.ReadExceptionStatus:
+		return 5
+	case global.WriteSingleCoil, global.WriteSingleRegister, global.WriteMultiCoils, global.WriteMultiRegisters,
+		global.GetCommEventCounter:
+		return 8
+	case 0x16:
+		// 屏蔽写寄存器
+		return 10
+	case global.Diagnostics, global.WriteFileRecord:
+		// 正常返回为请求的回显
+		return len(r.pdu) + 3
+	}
+
+	if c, ok := LookupFunCode(res[1]); ok {
+		return c.ResponseLen(res)
+	}
+	return -1
+}
+
+// DecodeResponse 解析正常返回报文
+// 写入功能码及之后、crc之前的数据
+func (r *RtuRawRequest) DecodeResponse(dst, res []byte) (int, error) {
+	return copy(dst, res[1:len(res)-2]), nil
+}
@@ -3,6 +3,7 @@ package request
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
 
 	"ckklearn.com/testmodbus/global"
 	"ckklearn.com/testmodbus/mbrtu/mbcrc"
@@ -50,3 +51,31 @@ func (r *RtuReadRequest) ExpectedLen() int {
 
 	return dataLen + 5
 }
+
+// RtuWideReadRequest 读数据请求
+// 请求报文与标准读请求相同，返回报文的字节数字段占2个字节，区别于标准mbrtu
+type RtuWideReadRequest struct {
+	RtuReadRequest
+}
+
+// NewRtuWideReadRequest 构造函数
+func NewRtuWideReadRequest(addr byte, fun global.FunCode, offset, num uint16) *RtuWideReadRequest {
+	return &RtuWideReadRequest{
+		RtuReadRequest: *NewRtuReadRequest(addr, fun, offset, num),
+	}
+}
+
+// ExpectedLen 期望的返回报文字节长度
+func (r *RtuWideReadRequest) ExpectedLen() int {
+	return r.RtuReadRequest.ExpectedLen() + 1
+}
+
+// DecodeResponse 解析正常返回报文
+// `res[2:4]` 是读取的数据的字节长度
+func (r *RtuWideReadRequest) DecodeResponse(dst, res []byte) (int, error) {
+	dataLen := int(binary.BigEndian.Uint16(res[2:]))
+	if 4+dataLen > len(res)-2 {
+		return 0, fmt.Errorf("invalid byte count %d in %d bytes response", dataLen, len(res))
+	}
+	return copy(dst, res[4:4+dataLen]), nil
+}
@@ -0,0 +1,61 @@
+// 仅用于串行链路的rtu请求结构
+// 包括读取异常状态、获取通信事件计数器和通信事件日志
+
+package request
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"ckklearn.com/testmodbus/global"
+	"ckklearn.com/testmodbus/mbrtu/mbcrc"
+)
+
+// RtuSerialLineRequest 串行链路请求
+// 请求报文只有从站号和功能码
+type RtuSerialLineRequest struct {
+	head
+}
+
+// NewRtuSerialLineRequest 构造函数
+// `fun` 只能是0x07、0x0b或0x0c
+func NewRtuSerialLineRequest(addr byte, fun global.FunCode) *RtuSerialLineRequest {
+	return &RtuSerialLineRequest{
+		head: head{
+			addr: addr,
+			fun:  fun,
+		},
+	}
+}
+
+// Serialize 将结构序列化为rtu请求报文
+func (r *RtuSerialLineRequest) Serialize(buf *bytes.Buffer, crcOrder binary.ByteOrder) error {
+	err := binary.Write(buf, binary.BigEndian, r.head)
+	if err != nil {
+		return err
+	}
+	crc16 := mbcrc.Crc16(buf.Bytes())
+	return binary.Write(buf, crcOrder, crc16)
+}
+
+// ExpectedLen 期望的返回报文字节长度
+// 通信事件日志的长度不定，这里返回不含事件的最小长度
+func (r *RtuSerialLineRequest) ExpectedLen() int {
+	switch r.fun {
+	case global.ReadExceptionStatus:
+		return 5
+	case global.GetCommEventCounter:
+		return 8
+	default:
+		return 11
+	}
+}
+
+// ResponseLen 根据返回报文计算完整长度
+// 通信事件日志的`res[2]`是数据的字节长度
+func (r *RtuSerialLineRequest) ResponseLen(res []byte) int {
+	if r.fun != global.GetCommEventLog {
+		return r.ExpectedLen()
+	}
+	return int(res[2]) + 5
+}
@@ -104,3 +104,56 @@ func (r *RtuWriteMultiRegsRequest) Serialize(buf *bytes.Buffer, crcOrder binary.
 func (r *RtuWriteMultiRegsRequest) ExpectedLen() int {
 	return 8
 }
+
+// ---- 字节数占2个字节的写寄存器 ----
+
+// 字节数字段占2个字节的写多个请求共用信息
+// 区别于标准mbrtu，用于部分厂家的变体
+type wideWriteMultiBase struct {
+	base
+	num      uint16
+	dataSize uint16
+}
+
+// RtuWideWriteMultiRegsRequest 写多个保持寄存器请求
+// 字节数字段占2个字节，区别于标准mbrtu
+type RtuWideWriteMultiRegsRequest struct {
+	wideWriteMultiBase
+	data []uint16
+}
+
+// NewRtuWideWriteMultiRegsRequest 构造函数
+func NewRtuWideWriteMultiRegsRequest(addr byte, offset uint16, data []uint16) *RtuWideWriteMultiRegsRequest {
+	regNum := uint16(len(data))
+	return &RtuWideWriteMultiRegsRequest{
+		wideWriteMultiBase: wideWriteMultiBase{
+			base: base{
+				addr:   addr,
+				fun:    global.WriteMultiRegisters,
+				offset: offset,
+			},
+			num:      regNum,
+			dataSize: regNum * 2,
+		},
+		data: data,
+	}
+}
+
+// Serialize 将结构序列化为rtu请求报文
+func (r *RtuWideWriteMultiRegsRequest) Serialize(buf *bytes.Buffer, crcOrder binary.ByteOrder) error {
+	err := binary.Write(buf, binary.BigEndian, r.wideWriteMultiBase)
+	if err != nil {
+		return err
+	}
+	err = binary.Write(buf, binary.BigEndian, r.data)
+	if err != nil {
+		return err
+	}
+	crc16 := mbcrc.Crc16(buf.Bytes())
+	return binary.Write(buf, crcOrder, crc16)
+}
+
+// ExpectedLen 期望的返回报文字节长度
+func (r *RtuWideWriteMultiRegsRequest) ExpectedLen() int {
+	return 8
+}
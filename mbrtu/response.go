@@ -4,30 +4,47 @@ import (
 	"fmt"
 
 	"ckklearn.com/testmodbus/global"
+	"ckklearn.com/testmodbus/mbrtu/request"
 )
 
 // RtuParseResponse 解析从站返回报文
+// 使用默认的异常码表解析从站异常
 func RtuParseResponse(dst, src []byte, reqFunCode global.FunCode) (int, error) {
+	return RtuParseResponseWith(dst, src, reqFunCode, global.DefaultExceptionTable)
+}
+
+// RtuParseResponseWith 解析从站返回报文
+// 使用指定的异常码表解析从站异常
+func RtuParseResponseWith(dst, src []byte, reqFunCode global.FunCode, table *global.ExceptionTable) (int, error) {
 	// `src[1]` 是读取的报文的功能码
 	switch src[1] {
 	case reqFunCode:
-		// 如果是读取，则写入读取到的数据，否则不写入
+		// 用户自定义功能码由注册的函数解析
+		if c, ok := request.LookupFunCode(reqFunCode); ok {
+			if c.Decode != nil {
+				return c.Decode(dst, src[2:len(src)-2])
+			}
+			return copy(dst, src[2:len(src)-2]), nil
+		}
+
+		// 如果是读取，则写入读取到的数据
 		// `src[2]` 是读取的数据的字节长度
 		switch reqFunCode {
-		case global.ReadCoils, global.ReadInputs, global.ReadInputRegisters, global.ReadHoldingRegisters:
+		case global.ReadCoils, global.ReadInputs, global.ReadInputRegisters, global.ReadHoldingRegisters,
+			global.GetCommEventLog, global.ReadFileRecord:
+			if 3+int(src[2]) != len(src)-2 {
+				return 0, fmt.Errorf("invalid byte count %d in %d bytes response", src[2], len(src))
+			}
 			return copy(dst, src[3:3+src[2]]), nil
 		default:
-			return 0, nil
+			// 其余功能码写入功能码之后、crc之前的全部数据
+			// 写请求通常传入nil，此时不写入任何数据
+			return copy(dst, src[2:len(src)-2]), nil
 		}
 
 	case reqFunCode + 0x80:
 		// 判断从站返回的异常类型
-		errCode := src[2]
-		err, ok := global.SlaveErrorMap[errCode]
-		if !ok {
-			return 0, fmt.Errorf("unknow error code `%x`", errCode)
-		}
-		return 0, err
+		return 0, table.Lookup(src[2])
 
 	default:
 		return 0, fmt.Errorf("internal error")
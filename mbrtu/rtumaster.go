@@ -3,12 +3,16 @@ package mbrtu
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
 	"sync"
+	"time"
 
 	"github.com/tarm/serial"
 
 	"ckklearn.com/testmodbus/global"
+	"ckklearn.com/testmodbus/mbrtu/mbcodec"
 	"ckklearn.com/testmodbus/mbrtu/mbcrc"
 	"ckklearn.com/testmodbus/mbrtu/request"
 )
@@ -16,25 +20,99 @@ import (
 const (
 	// rtu下返回报文最小长度（异常返回报文）
 	minResLen int = 5
+	// 标准rtu下报文最大长度，作为读取缓冲区的初始大小
+	maxAduLen int = 256
 )
 
+// ErrReadTimeout 串口读取超时
+var ErrReadTimeout = errors.New("read timeout")
+
+// CrcError 返回报文crc校验失败
+type CrcError struct {
+	ReadCrc uint16 // 报文中的crc
+	CalCrc  uint16 // 计算得到的crc
+}
+
+func (e CrcError) Error() string {
+	return fmt.Sprintf("validate failed: readcrc %x, calcrc %x", e.ReadCrc, e.CalCrc)
+}
+
 // RtuMaster modbus主站结构
 type RtuMaster struct {
 	s           *serial.Port
-	l           *sync.Mutex
-	reqCrcOrder binary.ByteOrder // 最后一次请求的crc16校验码字节序
-	reqFunCode  global.FunCode   // 最后一次请求的功能码
-	reqExpLen   int              // 最后一次请求的期望返回报文字节长度
+	l           *sync.Mutex                     // 总线锁
+	cl          *sync.RWMutex                   // 配置锁
+	reqCrcOrder binary.ByteOrder                // 最后一次请求的crc16校验码字节序
+	reqFunCode  global.FunCode                  // 最后一次请求的功能码
+	reqExpLen   int                             // 最后一次请求的期望返回报文字节长度
+	reqVarLen   request.RtuVarLenRequest        // 最后一次请求如果返回报文长度不定，则记录该请求
+	reqDecoder  request.RtuResponseDecoder      // 最后一次请求如果自行解析返回报文，则记录该请求
+	reqExcTable *global.ExceptionTable          // 最后一次请求使用的异常码表
+	excTables   map[byte]*global.ExceptionTable // 按从站号配置的异常码表
+	dialect     *Dialect                        // 默认的协议变体
+	dialects    map[byte]*Dialect               // 按从站号配置的协议变体
+	crcOrders   map[byte]binary.ByteOrder       // 自动识别得到的从站crc字节序
+	healthConf  HealthConfig                    // 从站健康跟踪的参数
+	health      map[byte]*SlaveHealth           // 按从站号记录的健康状态
+	readTimeout time.Duration                   // 串口配置的读取超时
+	timeoutConf TimeoutConfig                   // 自适应超时的参数
+	latency     map[LatencyKey]*latency         // 按从站和功能码统计的延时
+	reqTimeout  time.Duration                   // 最后一次请求的超时
+	deadline    time.Time                       // 最后一次请求等待返回数据的截止时间
 }
 
 // NewRtuMaster 构造函数
 // 串口在这里被初始化
+// 串口以较短的超时打开，`c.ReadTimeout` 作为等待返回数据的默认超时
 func NewRtuMaster(c *serial.Config) (*RtuMaster, error) {
-	s, err := serial.OpenPort(c)
+	pc := *c
+	if pc.ReadTimeout > readTick {
+		pc.ReadTimeout = readTick
+	}
+	s, err := serial.OpenPort(&pc)
 	if err != nil {
 		return nil, err
 	}
-	return &RtuMaster{s: s, l: new(sync.Mutex)}, nil
+	return &RtuMaster{
+		s:           s,
+		l:           new(sync.Mutex),
+		cl:          new(sync.RWMutex),
+		excTables:   make(map[byte]*global.ExceptionTable),
+		dialect:     StandardDialect,
+		dialects:    make(map[byte]*Dialect),
+		crcOrders:   make(map[byte]binary.ByteOrder),
+		health:      make(map[byte]*SlaveHealth),
+		readTimeout: c.ReadTimeout,
+		timeoutConf: TimeoutConfig{Window: defaultLatencyWindow},
+		latency:     make(map[LatencyKey]*latency),
+	}, nil
+}
+
+// SetExceptionTable 为从站配置异常码表
+// `t` 为nil时恢复使用默认的异常码表
+func (m *RtuMaster) SetExceptionTable(addr byte, t *global.ExceptionTable) {
+	m.cl.Lock()
+	defer m.cl.Unlock()
+	if t == nil {
+		delete(m.excTables, addr)
+		return
+	}
+	m.excTables[addr] = t
+}
+
+// 获取从站的异常码表
+// 优先使用为从站配置的异常码表，其次是请求使用的协议变体的异常码表
+func (m *RtuMaster) exceptionTable(addr byte, d *Dialect) *global.ExceptionTable {
+	m.cl.RLock()
+	t, ok := m.excTables[addr]
+	m.cl.RUnlock()
+	if ok {
+		return t
+	}
+	if d.Exceptions != nil {
+		return d.Exceptions
+	}
+	return global.DefaultExceptionTable
 }
 
 // Close 关闭主站
@@ -50,6 +128,11 @@ func (m *RtuMaster) write(r request.RtuRequest, crcOrder binary.ByteOrder) (int,
 	if err != nil {
 		return 0, err
 	}
+	// 丢弃之前的请求超时后才到达的数据
+	err = m.s.Flush()
+	if err != nil {
+		return 0, err
+	}
 	n, err := m.s.Write(buf.Bytes())
 	if err != nil {
 		return 0, err
@@ -57,26 +140,44 @@ func (m *RtuMaster) write(r request.RtuRequest, crcOrder binary.ByteOrder) (int,
 	m.reqCrcOrder = crcOrder
 	m.reqFunCode = r.FunCode()
 	m.reqExpLen = r.ExpectedLen()
+	m.reqVarLen, _ = r.(request.RtuVarLenRequest)
+	m.reqDecoder, _ = r.(request.RtuResponseDecoder)
 	return n, nil
 }
 
-// 将串口读取超时也作为异常抛出
-func (m *RtuMaster) _read(p []byte) (int, error) {
+// 串口读取一次
+// 部分平台在串口超时时返回`io.EOF`，统一为读取到0个字节
+func (m *RtuMaster) readOnce(p []byte) (int, error) {
 	n, err := m.s.Read(p)
-	if err != nil {
-		return 0, err
+	if err == io.EOF {
+		return 0, nil
 	}
-	if n == 0 {
-		return 0, fmt.Errorf("read timeout")
+	return n, err
+}
+
+// 将串口读取超时也作为异常抛出
+// 在请求的超时内循环读取，每次读到数据后重新计时
+func (m *RtuMaster) _read(p []byte) (int, error) {
+	for {
+		n, err := m.readOnce(p)
+		if err != nil {
+			return 0, err
+		}
+		if n > 0 {
+			m.deadline = time.Now().Add(m.reqTimeout)
+			return n, nil
+		}
+		if !time.Now().Before(m.deadline) {
+			return 0, ErrReadTimeout
+		}
 	}
-	return n, nil
 }
 
 // 读取串口数据
 // 包含crc校验和对读取数据的截取
 func (m *RtuMaster) read(p []byte) (int, error) {
 	read := 0
-	raw := make([]byte, 1024)
+	raw := make([]byte, maxAduLen)
 
 	// 先保证读到最小长度的报文
 	for read < minResLen {
@@ -89,13 +190,36 @@ func (m *RtuMaster) read(p []byte) (int, error) {
 
 	// 如果不是异常，则继续读取剩余报文
 	if raw[1] != m.reqFunCode+0x80 {
-		for read < m.reqExpLen {
+		expLen := m.reqExpLen
+		if m.reqVarLen != nil {
+			expLen = m.reqVarLen.ResponseLen(raw[:read])
+		}
+		// 非标准的长报文超过缓冲区时扩充缓冲区
+		if expLen > len(raw) {
+			raw = append(raw, make([]byte, expLen-len(raw))...)
+		}
+		for read < expLen {
 			n, err := m._read(raw[read:])
 			if err != nil {
 				return 0, err
 			}
 			read += n
 		}
+
+		// 无法推断长度时，读取到串口超时（帧间隔）为止
+		for expLen < 0 {
+			if read == len(raw) {
+				raw = append(raw, make([]byte, maxAduLen)...)
+			}
+			n, err := m.readOnce(raw[read:])
+			if err != nil {
+				return 0, err
+			}
+			if n == 0 {
+				break
+			}
+			read += n
+		}
 	}
 
 	// 利用crc16校验接收包
@@ -103,11 +227,14 @@ func (m *RtuMaster) read(p []byte) (int, error) {
 	binary.Read(bytes.NewReader(raw[read-2:]), m.reqCrcOrder, &readCrc)
 	calCrc := mbcrc.Crc16(raw[:read-2])
 	if readCrc != calCrc {
-		return 0, fmt.Errorf("validate failed: readcrc %x, calcrc %x", readCrc, calCrc)
+		return 0, CrcError{ReadCrc: readCrc, CalCrc: calCrc}
 	}
 
 	// 解析从站返回数据
-	n, err := RtuParseResponse(p, raw, m.reqFunCode)
+	if m.reqDecoder != nil && raw[1] == m.reqFunCode {
+		return m.reqDecoder.DecodeResponse(p, raw[:read])
+	}
+	n, err := RtuParseResponseWith(p, raw[:read], m.reqFunCode, m.reqExcTable)
 	if err != nil {
 		return 0, err
 	}
@@ -115,16 +242,118 @@ func (m *RtuMaster) read(p []byte) (int, error) {
 }
 
 // BaseReadWrite 基础的modbus通信函数
+// 从站的协议变体指定了crc字节序时，使用变体的crc字节序
+// `crcOrder` 为`AutoCrcOrder`时自动识别从站的crc字节序
+// 离线的从站不到探测时间时直接返回`ErrSlaveOffline`
 func (m *RtuMaster) BaseReadWrite(p []byte, r request.RtuRequest, crcOrder binary.ByteOrder) (int, error) {
+	return m.readWrite(p, r, nil, crcOrder, false)
+}
+
+// ForceReadWrite 与`BaseReadWrite`相同，但离线的从站也发送请求
+func (m *RtuMaster) ForceReadWrite(p []byte, r request.RtuRequest, crcOrder binary.ByteOrder) (int, error) {
+	return m.readWrite(p, r, nil, crcOrder, true)
+}
+
+// 请求的从站号
+// 请求未实现`request.RtuAddrRequest`时取序列化报文的第1个字节，序列化失败时视为广播
+func requestAddr(r request.RtuRequest) byte {
+	if ar, ok := r.(request.RtuAddrRequest); ok {
+		return ar.Addr()
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, 8))
+	if r.Serialize(buf, binary.LittleEndian) != nil || buf.Len() == 0 {
+		return 0
+	}
+	return buf.Bytes()[0]
+}
+
+// 检查从站健康状态后发送请求，并按结果更新健康状态
+// `d` 为请求使用的协议变体，为nil时使用从站配置的协议变体
+// 广播和不返回报文的请求不影响健康状态
+func (m *RtuMaster) readWrite(p []byte, r request.RtuRequest, d *Dialect, crcOrder binary.ByteOrder, force bool) (int, error) {
+	addr := requestAddr(r)
+	if d == nil {
+		d = m.dialectFor(addr)
+	}
+	track := addr != 0 && r.ExpectedLen() != 0
+	if track && !force {
+		err := m.checkHealth(addr)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	var n int
+	var err error
+	crcOrder = d.crcOrder(crcOrder)
+	if crcOrder == AutoCrcOrder {
+		n, err = m.autoCrcReadWrite(p, r, addr, d)
+	} else {
+		n, err = m.baseReadWrite(p, r, addr, d, crcOrder)
+	}
+	if track {
+		m.updateHealth(addr, err)
+	}
+	return n, err
+}
+
+// 占用总线，写入请求并读取返回报文
+func (m *RtuMaster) baseReadWrite(p []byte, r request.RtuRequest, addr byte, d *Dialect, crcOrder binary.ByteOrder) (int, error) {
 	m.l.Lock()
 	defer m.l.Unlock()
 
+	fun := r.FunCode()
+	m.reqTimeout = m.requestTimeout(addr, fun)
+	m.reqExcTable = m.exceptionTable(addr, d)
+	start := time.Now()
 	_, err := m.write(r, crcOrder)
 	if err != nil {
 		return 0, err
 	}
 
-	return m.read(p)
+	// 从站不返回报文的请求，写入后直接返回
+	if m.reqExpLen == 0 {
+		return 0, nil
+	}
+
+	m.deadline = time.Now().Add(m.reqTimeout)
+	n, err := m.read(p)
+
+	// 收到正常返回或异常返回时记录往返延时
+	var slaveErr global.SlaveError
+	switch {
+	case err == nil || errors.As(err, &slaveErr):
+		m.recordLatency(addr, fun, time.Since(start), false)
+	case errors.Is(err, ErrReadTimeout):
+		m.recordLatency(addr, fun, m.reqTimeout, true)
+	}
+	return n, err
+}
+
+// RawReadWrite 发送原始pdu（功能码加数据）并返回从站返回的原始pdu
+// 从站号和crc由主站处理，异常返回作为错误抛出
+// 返回报文长度无法推断时以串口读取超时作为帧结束，因此需要设置串口的读取超时
+func (m *RtuMaster) RawReadWrite(addr byte, pdu []byte, crcOrder binary.ByteOrder) ([]byte, error) {
+	r, err := request.NewRtuRawRequest(addr, pdu)
+	if err != nil {
+		return nil, err
+	}
+	p := make([]byte, request.MaxPduLen)
+	n, err := m.BaseReadWrite(p, r, crcOrder)
+	if err != nil {
+		return nil, err
+	}
+	return p[:n], nil
+}
+
+// CustomReadWrite 发送用户自定义功能码请求
+// 功能码需要先通过`request.RegisterFunCode`注册，`args` 传给注册的编码函数
+func (m *RtuMaster) CustomReadWrite(p []byte, addr byte, fun global.FunCode, args interface{}, crcOrder binary.ByteOrder) (int, error) {
+	r, err := request.NewRtuCustomRequest(addr, fun, args)
+	if err != nil {
+		return 0, err
+	}
+	return m.BaseReadWrite(p, r, crcOrder)
 }
 
 // ---- 标准mbrtu ----
@@ -133,7 +362,7 @@ func (m *RtuMaster) BaseReadWrite(p []byte, r request.RtuRequest, crcOrder binar
 func (m *RtuMaster) ReadCoils(p []byte, addr byte, offset, num uint16, crcOrder binary.ByteOrder) (int, error) {
 	return m.BaseReadWrite(
 		p,
-		request.NewRtuReadRequest(addr, global.ReadCoils, offset, num),
+		m.dialectFor(addr).readRequest(addr, global.ReadCoils, offset, num),
 		crcOrder,
 	)
 }
@@ -142,7 +371,7 @@ func (m *RtuMaster) ReadCoils(p []byte, addr byte, offset, num uint16, crcOrder
 func (m *RtuMaster) ReadInputs(p []byte, addr byte, offset, num uint16, crcOrder binary.ByteOrder) (int, error) {
 	return m.BaseReadWrite(
 		p,
-		request.NewRtuReadRequest(addr, global.ReadInputs, offset, num),
+		m.dialectFor(addr).readRequest(addr, global.ReadInputs, offset, num),
 		crcOrder,
 	)
 }
@@ -151,7 +380,7 @@ func (m *RtuMaster) ReadInputs(p []byte, addr byte, offset, num uint16, crcOrder
 func (m *RtuMaster) ReadHoldingRegisters(p []byte, addr byte, offset, num uint16, crcOrder binary.ByteOrder) (int, error) {
 	return m.BaseReadWrite(
 		p,
-		request.NewRtuReadRequest(addr, global.ReadHoldingRegisters, offset, num),
+		m.dialectFor(addr).readRequest(addr, global.ReadHoldingRegisters, offset, num),
 		crcOrder,
 	)
 }
@@ -160,11 +389,44 @@ func (m *RtuMaster) ReadHoldingRegisters(p []byte, addr byte, offset, num uint16
 func (m *RtuMaster) ReadInputRegisters(p []byte, addr byte, offset, num uint16, crcOrder binary.ByteOrder) (int, error) {
 	return m.BaseReadWrite(
 		p,
-		request.NewRtuReadRequest(addr, global.ReadInputRegisters, offset, num),
+		m.dialectFor(addr).readRequest(addr, global.ReadInputRegisters, offset, num),
+		crcOrder,
+	)
+}
+
+// ReadArea 读取指定数据区
+// 按数据区选择对应的读取功能码
+func (m *RtuMaster) ReadArea(p []byte, addr byte, area global.Area, offset, num uint16, crcOrder binary.ByteOrder) (int, error) {
+	return m.BaseReadWrite(
+		p,
+		m.dialectFor(addr).readRequest(addr, area.ReadFunCode(), offset, num),
 		crcOrder,
 	)
 }
 
+// ReadAreaBytes 读取指定数据区，返回读取的数据
+// 线圈和离散输入为按位打包的字节，个数超过单次读取的上限或从站返回的数据长度不符时返回错误
+func (m *RtuMaster) ReadAreaBytes(addr byte, area global.Area, offset, num uint16, crcOrder binary.ByteOrder) ([]byte, error) {
+	max := m.dialectFor(addr).maxReadNum(area)
+	if num == 0 || int(num) > max {
+		return nil, fmt.Errorf("read num %d out of range 1~%d", num, max)
+	}
+	expLen := int(num) * 2
+	if area.IsBit() {
+		expLen = (int(num) + 7) / 8
+	}
+	// 多留1个字节，以便发现从站返回的数据过长
+	p := make([]byte, expLen+1)
+	n, err := m.ReadArea(p, addr, area, offset, num, crcOrder)
+	if err != nil {
+		return nil, err
+	}
+	if n != expLen {
+		return nil, fmt.Errorf("invalid response length %d, expected %d", n, expLen)
+	}
+	return p[:n], nil
+}
+
 // WriteSingleCoil 写单个线圈
 func (m *RtuMaster) WriteSingleCoil(addr byte, offset uint16, on bool, crcOrder binary.ByteOrder) error {
 	var data uint16
@@ -173,7 +435,7 @@ func (m *RtuMaster) WriteSingleCoil(addr byte, offset uint16, on bool, crcOrder
 	}
 	_, err := m.BaseReadWrite(
 		nil,
-		request.NewRtuWriteSingleRequest(addr, global.WriteSingleCoil, offset, data),
+		m.dialectFor(addr).writeSingleRequest(addr, global.WriteSingleCoil, offset, data),
 		crcOrder,
 	)
 	return err
@@ -183,7 +445,7 @@ func (m *RtuMaster) WriteSingleCoil(addr byte, offset uint16, on bool, crcOrder
 func (m *RtuMaster) WriteSingleRegister(addr byte, offset uint16, data uint16, crcOrder binary.ByteOrder) error {
 	_, err := m.BaseReadWrite(
 		nil,
-		request.NewRtuWriteSingleRequest(addr, global.WriteSingleRegister, offset, data),
+		m.dialectFor(addr).writeSingleRequest(addr, global.WriteSingleRegister, offset, data),
 		crcOrder,
 	)
 	return err
@@ -191,18 +453,9 @@ func (m *RtuMaster) WriteSingleRegister(addr byte, offset uint16, data uint16, c
 
 // WriteMultiCoils 写多个线圈
 func (m *RtuMaster) WriteMultiCoils(addr byte, offset uint16, on []bool, crcOrder binary.ByteOrder) error {
-	coilNum := len(on)
-	// 线圈数除以8再向上取整，得到字节数
-	data := make([]byte, (coilNum-1)/8+1)
-	for i, b := range on {
-		if b {
-			idx := i / 8
-			data[idx] += 1 << (i - 8*idx)
-		}
-	}
 	_, err := m.BaseReadWrite(
 		nil,
-		request.NewRtuWriteMultiCoilsRequest(addr, offset, uint16(coilNum), data),
+		m.dialectFor(addr).writeMultiCoilsRequest(addr, offset, uint16(len(on)), mbcodec.EncodeBools(on)),
 		crcOrder,
 	)
 	return err
@@ -210,22 +463,21 @@ func (m *RtuMaster) WriteMultiCoils(addr byte, offset uint16, on []bool, crcOrde
 
 // WriteMultiRegisters 写多个保持寄存器
 func (m *RtuMaster) WriteMultiRegisters(addr byte, offset uint16, data []uint16, crcOrder binary.ByteOrder) error {
-	_, err := m.BaseReadWrite(
-		nil,
-		request.NewRtuWriteMultiRegsRequest(addr, offset, data),
-		crcOrder,
-	)
+	return m.writeMultiRegisters(m.dialectFor(addr), addr, offset, data, crcOrder)
+}
+
+// 按指定的协议变体写多个保持寄存器
+// crc字节序等设置也使用`d`，而不是从站配置的协议变体
+func (m *RtuMaster) writeMultiRegisters(d *Dialect, addr byte, offset uint16, data []uint16, crcOrder binary.ByteOrder) error {
+	_, err := m.readWrite(nil, d.writeMultiRegsRequest(addr, offset, data), d, crcOrder, false)
 	return err
 }
 
 // ---- 南瑞项目变体 ----
 
-// NRWriteMultiRegisters 写多个保持寄存器
+// NRWriteMultiRegisters 按南瑞项目变体写多个保持寄存器
+//
+// Deprecated: 使用`SetSlaveDialect(addr, NRDialect)`后调用`WriteMultiRegisters`
 func (m *RtuMaster) NRWriteMultiRegisters(addr byte, offset uint16, data []uint16, crcOrder binary.ByteOrder) error {
-	_, err := m.BaseReadWrite(
-		nil,
-		request.NewNRWriteMultiRegsRequest(addr, offset, data),
-		crcOrder,
-	)
-	return err
+	return m.writeMultiRegisters(NRDialect, addr, offset, data, crcOrder)
 }
@@ -0,0 +1,141 @@
+package mbrtu
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"ckklearn.com/testmodbus/global"
+	"ckklearn.com/testmodbus/mbrtu/request"
+)
+
+// CommEventKind 通信事件类型
+type CommEventKind byte
+
+// 通信事件类型
+const (
+	CommEventReceive         CommEventKind = iota // 从站接收事件
+	CommEventSend                                 // 从站发送事件
+	CommEventEnterListenOnly                      // 从站进入只听模式
+	CommEventRestart                              // 从站通信重启
+)
+
+// 接收事件的标志位
+const (
+	EventRecvCommError   byte = 0x02 // 通信错误
+	EventRecvCharOverrun byte = 0x10 // 字符溢出
+	EventRecvListenOnly  byte = 0x20 // 处于只听模式
+	EventRecvBroadcast   byte = 0x40 // 收到广播
+)
+
+// 发送事件的标志位
+const (
+	EventSendReadException  byte = 0x01 // 发送了读异常（异常码1-3）
+	EventSendAbortException byte = 0x02 // 发送了从站故障异常（异常码4）
+	EventSendBusyException  byte = 0x04 // 发送了从站忙异常（异常码5-6）
+	EventSendNAKException   byte = 0x08 // 发送了否定确认异常（异常码7）
+	EventSendWriteTimeout   byte = 0x10 // 写超时
+	EventSendListenOnly     byte = 0x20 // 处于只听模式
+)
+
+// CommEvent 解析后的单个通信事件
+type CommEvent struct {
+	Raw  byte          // 原始事件字节
+	Kind CommEventKind // 事件类型
+}
+
+// Has 判断事件是否包含指定标志位
+// 标志位只对接收和发送事件有意义
+func (e CommEvent) Has(flag byte) bool {
+	if e.Kind != CommEventReceive && e.Kind != CommEventSend {
+		return false
+	}
+	return e.Raw&flag != 0
+}
+
+// 解析单个事件字节
+func parseCommEvent(b byte) CommEvent {
+	e := CommEvent{Raw: b}
+	switch {
+	case b&0x80 != 0:
+		e.Kind = CommEventReceive
+	case b&0x40 != 0:
+		e.Kind = CommEventSend
+	case b == 0x04:
+		e.Kind = CommEventEnterListenOnly
+	default:
+		e.Kind = CommEventRestart
+	}
+	return e
+}
+
+// CommEventLog 通信事件日志
+type CommEventLog struct {
+	Status       uint16      // 状态字，0xffff表示从站正在处理上一条命令
+	EventCount   uint16      // 事件计数器
+	MessageCount uint16      // 报文计数器
+	Events       []CommEvent // 事件列表，第一个为最新的事件
+}
+
+// ReadExceptionStatus 读取从站异常状态
+// 返回8个异常状态位组成的字节
+func (m *RtuMaster) ReadExceptionStatus(addr byte, crcOrder binary.ByteOrder) (byte, error) {
+	p := make([]byte, 1)
+	n, err := m.BaseReadWrite(
+		p,
+		request.NewRtuSerialLineRequest(addr, global.ReadExceptionStatus),
+		crcOrder,
+	)
+	if err != nil {
+		return 0, err
+	}
+	if n != 1 {
+		return 0, fmt.Errorf("invalid exception status length %d", n)
+	}
+	return p[0], nil
+}
+
+// GetCommEventCounter 获取通信事件计数器
+// 返回状态字和事件计数器
+func (m *RtuMaster) GetCommEventCounter(addr byte, crcOrder binary.ByteOrder) (uint16, uint16, error) {
+	p := make([]byte, 4)
+	n, err := m.BaseReadWrite(
+		p,
+		request.NewRtuSerialLineRequest(addr, global.GetCommEventCounter),
+		crcOrder,
+	)
+	if err != nil {
+		return 0, 0, err
+	}
+	if n != 4 {
+		return 0, 0, fmt.Errorf("invalid comm event counter length %d", n)
+	}
+	return binary.BigEndian.Uint16(p), binary.BigEndian.Uint16(p[2:]), nil
+}
+
+// GetCommEventLog 获取通信事件日志
+func (m *RtuMaster) GetCommEventLog(addr byte, crcOrder binary.ByteOrder) (*CommEventLog, error) {
+	// 数据最长为6字节的计数器加64字节的事件
+	p := make([]byte, 70)
+	n, err := m.BaseReadWrite(
+		p,
+		request.NewRtuSerialLineRequest(addr, global.GetCommEventLog),
+		crcOrder,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if n < 6 {
+		return nil, fmt.Errorf("invalid comm event log length %d", n)
+	}
+
+	log := &CommEventLog{
+		Status:       binary.BigEndian.Uint16(p),
+		EventCount:   binary.BigEndian.Uint16(p[2:]),
+		MessageCount: binary.BigEndian.Uint16(p[4:]),
+		Events:       make([]CommEvent, 0, n-6),
+	}
+	for _, b := range p[6:n] {
+		log.Events = append(log.Events, parseCommEvent(b))
+	}
+	return log, nil
+}
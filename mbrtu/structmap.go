@@ -0,0 +1,401 @@
+package mbrtu
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"ckklearn.com/testmodbus/global"
+	"ckklearn.com/testmodbus/mbrtu/mbcodec"
+)
+
+// 结构体字段的标签名
+// 格式为`modbus:"数据区,偏移量[,类型][,排列顺序][,bcd][,选项=值...]"`
+// 例如`modbus:"hr,100,float32,cdab,scale=0.1"`，数据区和偏移量也可以写成一个地址，如`modbus:"40101,float32"`
+// 选项有scale（增益）、bias（偏移）和invalid（无效值，多个用`|`分隔）
+// 字段类型为`mbcodec.Value`时写入转换后的完整结果
+const structTag = "modbus"
+
+var valueType = reflect.TypeOf(mbcodec.Value{})
+
+// 结构体字段与数据区的映射
+type fieldMap struct {
+	index  int                // 字段在结构体中的序号
+	name   string             // 字段名
+	area   global.Area        // 数据区
+	offset uint16             // 偏移量
+	typ    mbcodec.Type       // 数值类型
+	order  mbcodec.Order      // 多寄存器数值的排列顺序
+	conv   *mbcodec.Converter // 工程转换
+}
+
+// 字段占用的寄存器或位的个数
+func (f *fieldMap) size() uint16 {
+	if f.area.IsBit() {
+		return 1
+	}
+	return uint16(f.typ.Registers())
+}
+
+// 解析单个字段的标签
+func parseFieldTag(name, tag string) (*fieldMap, error) {
+	parts := strings.Split(tag, ",")
+
+	// 第一部分不是数据区名称时按地址解析
+	var a global.Address
+	area, err := global.ParseArea(strings.TrimSpace(parts[0]))
+	if err == nil {
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("field %s: tag requires area and offset", name)
+		}
+		offset, err := strconv.ParseUint(strings.TrimSpace(parts[1]), 0, 16)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: invalid offset `%s`", name, parts[1])
+		}
+		a = global.Address{Area: area, Offset: uint16(offset)}
+		parts = parts[2:]
+	} else {
+		a, err = global.ParseAddress(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %v", name, err)
+		}
+		area = a.Area
+		parts = parts[1:]
+	}
+	f := &fieldMap{name: name, area: area, offset: a.Offset, conv: new(mbcodec.Converter)}
+
+	// 其余部分依次为类型、排列顺序和选项，类型和排列顺序可以省略
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "bcd" {
+			f.conv.BCD = true
+			continue
+		}
+		if kv := strings.SplitN(part, "=", 2); len(kv) == 2 {
+			err = f.setOption(kv[0], kv[1])
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %v", name, err)
+			}
+			continue
+		}
+		if typ, err := mbcodec.ParseType(part); err == nil && f.typ == "" {
+			f.typ = typ
+			continue
+		}
+		order, err := mbcodec.ParseOrder(part)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: unknown tag part `%s`", name, part)
+		}
+		f.order = order
+	}
+
+	if f.typ == "" {
+		if area.IsBit() {
+			f.typ = mbcodec.Bool
+		} else {
+			f.typ = mbcodec.Uint16
+		}
+	}
+	if area.IsBit() && (f.typ != mbcodec.Bool || !f.conv.IsIdentity() || f.conv.Invalid != nil) {
+		return nil, fmt.Errorf("field %s: area %s only supports plain bool", name, area)
+	}
+	return f, nil
+}
+
+// 设置标签中的选项
+func (f *fieldMap) setOption(key, value string) error {
+	switch key {
+	case "scale":
+		scale, err := strconv.ParseFloat(value, 64)
+		if err != nil || scale == 0 {
+			return fmt.Errorf("invalid scale `%s`", value)
+		}
+		f.conv.Gain = scale
+	case "bias":
+		bias, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid bias `%s`", value)
+		}
+		f.conv.Offset = bias
+	case "invalid":
+		for _, s := range strings.Split(value, "|") {
+			inv, err := strconv.ParseUint(s, 0, 64)
+			if err != nil {
+				return fmt.Errorf("invalid sentinel `%s`", s)
+			}
+			f.conv.Invalid = append(f.conv.Invalid, inv)
+		}
+	default:
+		return fmt.Errorf("unknown option `%s`", key)
+	}
+	return nil
+}
+
+// 解析结构体所有带标签的字段
+func parseStructFields(t reflect.Type) ([]*fieldMap, error) {
+	var fields []*fieldMap
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup(structTag)
+		if !ok || tag == "-" {
+			continue
+		}
+		if sf.PkgPath != "" {
+			return nil, fmt.Errorf("field %s: unexported field cannot be mapped", sf.Name)
+		}
+		f, err := parseFieldTag(sf.Name, tag)
+		if err != nil {
+			return nil, err
+		}
+		f.index = i
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// 获取结构体指针指向的结构体
+func structValue(v interface{}) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("expected a non-nil pointer to struct, got %T", v)
+	}
+	return rv.Elem(), nil
+}
+
+// 一次读取请求覆盖的连续区间
+type readBlock struct {
+	area   global.Area
+	start  uint16
+	num    uint16
+	fields []*fieldMap
+}
+
+// 计算读取所有字段所需的最少读取请求
+// 同一数据区中连续或重叠的字段合并为一次读取，单次读取不超过数据区的最大个数
+func planReadBlocks(fields []*fieldMap) []*readBlock {
+	wants := make([]Span, len(fields))
+	for i, f := range fields {
+		wants[i] = Span{Fun: f.area.ReadFunCode(), Offset: f.offset, Num: f.size()}
+	}
+	// 字段的大小不超过上限，不会返回错误
+	merged, _ := Coalesce(wants, CoalesceOptions{})
+
+	blocks := make([]*readBlock, len(merged))
+	for i, m := range merged {
+		blk := &readBlock{start: m.Offset, num: m.Num}
+		for _, j := range m.Wants {
+			blk.area = fields[j].area
+			blk.fields = append(blk.fields, fields[j])
+		}
+		blocks[i] = blk
+	}
+	return blocks
+}
+
+// 将转换后的数值写入字段
+// 没有工程转换时使用原始值，避免大整数经过浮点数丢失精度
+// 无效值写入浮点数字段时为NaN，写入其它类型字段时返回错误
+func setFieldValue(fv reflect.Value, v mbcodec.Value, identity bool) error {
+	if fv.Type() == valueType {
+		fv.Set(reflect.ValueOf(v))
+		return nil
+	}
+	if !v.Valid {
+		if fv.Kind() == reflect.Float32 || fv.Kind() == reflect.Float64 {
+			fv.SetFloat(math.NaN())
+			return nil
+		}
+		return fmt.Errorf("invalid value %x", v.Bits)
+	}
+
+	val := v.Raw
+	if !identity {
+		val = v.Float
+	}
+	return setFieldNumber(fv, val)
+}
+
+// 将数值写入基本类型的字段
+func setFieldNumber(fv reflect.Value, val interface{}) error {
+	switch fv.Kind() {
+	case reflect.Bool:
+		f, _ := mbcodec.ToFloat64(val)
+		fv.SetBool(f != 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		switch x := val.(type) {
+		case int64:
+			i = x
+		case uint64:
+			i = int64(x)
+		default:
+			f, _ := mbcodec.ToFloat64(val)
+			i = int64(math.Round(f))
+		}
+		if fv.OverflowInt(i) {
+			return fmt.Errorf("value %v overflows %s", val, fv.Type())
+		}
+		fv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var u uint64
+		switch x := val.(type) {
+		case uint64:
+			u = x
+		case int64:
+			if x < 0 {
+				return fmt.Errorf("value %v overflows %s", val, fv.Type())
+			}
+			u = uint64(x)
+		default:
+			f, _ := mbcodec.ToFloat64(val)
+			if f < 0 {
+				return fmt.Errorf("value %v overflows %s", val, fv.Type())
+			}
+			u = uint64(math.Round(f))
+		}
+		if fv.OverflowUint(u) {
+			return fmt.Errorf("value %v overflows %s", val, fv.Type())
+		}
+		fv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, _ := mbcodec.ToFloat64(val)
+		fv.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+	return nil
+}
+
+// 读取字段的值
+func fieldValue(fv reflect.Value) (interface{}, error) {
+	switch fv.Kind() {
+	case reflect.Bool:
+		return fv.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return fv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fv.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		return fv.Float(), nil
+	}
+	if fv.Type() == valueType {
+		return fv.Interface().(mbcodec.Value).Float, nil
+	}
+	return nil, fmt.Errorf("unsupported field type %s", fv.Type())
+}
+
+// ReadStruct 按结构体字段的`modbus`标签读取从站数据
+// `v` 为结构体指针，同一数据区中连续的字段合并为一次读取
+func (m *RtuMaster) ReadStruct(addr byte, v interface{}, crcOrder binary.ByteOrder) error {
+	rv, err := structValue(v)
+	if err != nil {
+		return err
+	}
+	fields, err := parseStructFields(rv.Type())
+	if err != nil {
+		return err
+	}
+
+	for _, blk := range planReadBlocks(fields) {
+		p, err := m.ReadAreaBytes(addr, blk.area, blk.start, blk.num, crcOrder)
+		if err != nil {
+			return err
+		}
+
+		var bits []bool
+		if blk.area.IsBit() {
+			bits, err = mbcodec.Bools(p, int(blk.num))
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, f := range blk.fields {
+			idx := int(f.offset - blk.start)
+			if blk.area.IsBit() {
+				err = setFieldNumber(rv.Field(f.index), bits[idx])
+			} else {
+				var v mbcodec.Value
+				v, err = f.conv.Convert(p[idx*2:(idx+int(f.size()))*2], f.typ, f.order)
+				if err == nil {
+					err = setFieldValue(rv.Field(f.index), v, f.conv.IsIdentity())
+				}
+			}
+			if err != nil {
+				return fmt.Errorf("field %s: %v", f.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// WriteStruct 按结构体字段的`modbus`标签将字段写入从站
+// `names` 为要写入的字段名，为空时写入所有线圈和保持寄存器字段
+// 线圈使用写单个线圈，单个寄存器使用写单个寄存器，多个寄存器使用写多个寄存器
+func (m *RtuMaster) WriteStruct(addr byte, v interface{}, crcOrder binary.ByteOrder, names ...string) error {
+	rv, err := structValue(v)
+	if err != nil {
+		return err
+	}
+	fields, err := parseStructFields(rv.Type())
+	if err != nil {
+		return err
+	}
+
+	selected := fields
+	if len(names) > 0 {
+		byName := make(map[string]*fieldMap, len(fields))
+		for _, f := range fields {
+			byName[f.name] = f
+		}
+		selected = make([]*fieldMap, 0, len(names))
+		for _, name := range names {
+			f, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("field %s has no modbus tag", name)
+			}
+			if !f.area.Writable() {
+				return fmt.Errorf("field %s: area %s is read-only", name, f.area)
+			}
+			selected = append(selected, f)
+		}
+	}
+
+	for _, f := range selected {
+		if !f.area.Writable() {
+			continue
+		}
+		val, err := fieldValue(rv.Field(f.index))
+		if err != nil {
+			return fmt.Errorf("field %s: %v", f.name, err)
+		}
+
+		if f.area == global.AreaCoil {
+			on, _ := mbcodec.ToFloat64(val)
+			err = m.WriteSingleCoil(addr, f.offset, on != 0, crcOrder)
+		} else {
+			var regs []uint16
+			if f.conv.IsIdentity() {
+				regs, err = mbcodec.Encode(val, f.typ, f.order)
+			} else {
+				num, _ := mbcodec.ToFloat64(val)
+				regs, err = f.conv.EncodeValue(num, f.typ, f.order)
+			}
+			if err != nil {
+				return fmt.Errorf("field %s: %v", f.name, err)
+			}
+			if len(regs) == 1 {
+				err = m.WriteSingleRegister(addr, f.offset, regs[0], crcOrder)
+			} else {
+				err = m.WriteMultiRegisters(addr, f.offset, regs, crcOrder)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("field %s: %v", f.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package mbrtu
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"ckklearn.com/testmodbus/global"
+)
+
+// 串口单次读取的超时
+// 主站按请求的超时循环读取，linux下串口超时的精度为100毫秒
+const readTick = 20 * time.Millisecond
+
+// 默认保留的延时样本个数
+const defaultLatencyWindow = 100
+
+// 延时直方图的分桶上界
+var latencyBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	20 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	200 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2 * time.Second,
+	5 * time.Second,
+}
+
+// TimeoutConfig 自适应超时的参数
+// 按从站和功能码统计请求的往返延时，超时取延时的分位数加上余量，并限制在上下限之间
+type TimeoutConfig struct {
+	Percentile float64       // 分位数，如0.95，为0时不使用自适应超时
+	Margin     time.Duration // 在分位数上增加的余量
+	Floor      time.Duration // 超时的下限，linux下串口超时的精度为100毫秒，低于100毫秒的下限按100毫秒生效
+	Ceiling    time.Duration // 超时的上限，为0时使用串口配置的读取超时
+	MinSamples int           // 样本少于这个数时使用串口配置的读取超时
+	Window     int           // 计算分位数时使用最近的样本个数，为0时为100
+}
+
+// LatencyKey 延时统计的分组
+type LatencyKey struct {
+	Slave byte
+	Fun   global.FunCode
+}
+
+// LatencyBucket 延时直方图的一个分桶
+type LatencyBucket struct {
+	Le    time.Duration // 分桶上界，最后一个分桶为0，表示无上界
+	Count int
+}
+
+// LatencyStats 延时统计
+type LatencyStats struct {
+	Count   int             // 成功请求的总数
+	Min     time.Duration   // 最小延时
+	Max     time.Duration   // 最大延时
+	Buckets []LatencyBucket // 延时直方图
+	Timeout time.Duration   // 当前使用的超时
+}
+
+// 延时统计的运行状态
+type latency struct {
+	stats   LatencyStats
+	samples []time.Duration // 最近的样本，环形缓冲
+	next    int
+}
+
+// SetTimeoutConfig 配置自适应超时
+// 只有以非0的读取超时打开串口时才生效
+func (m *RtuMaster) SetTimeoutConfig(c TimeoutConfig) {
+	if c.Window <= 0 {
+		c.Window = defaultLatencyWindow
+	}
+	m.cl.Lock()
+	defer m.cl.Unlock()
+	m.timeoutConf = c
+	for _, l := range m.latency {
+		l.samples, l.next = nil, 0
+		l.stats.Timeout = m.timeoutFor(l)
+	}
+}
+
+// Latency 从站某个功能码的延时统计
+func (m *RtuMaster) Latency(addr byte, fun global.FunCode) (LatencyStats, bool) {
+	m.cl.RLock()
+	defer m.cl.RUnlock()
+	l, ok := m.latency[LatencyKey{Slave: addr, Fun: fun}]
+	if !ok {
+		return LatencyStats{}, false
+	}
+	return l.stats.copy(), true
+}
+
+// Latencies 所有从站和功能码的延时统计
+func (m *RtuMaster) Latencies() map[LatencyKey]LatencyStats {
+	m.cl.RLock()
+	defer m.cl.RUnlock()
+	res := make(map[LatencyKey]LatencyStats, len(m.latency))
+	for k, l := range m.latency {
+		res[k] = l.stats.copy()
+	}
+	return res
+}
+
+func (s LatencyStats) copy() LatencyStats {
+	s.Buckets = append([]LatencyBucket(nil), s.Buckets...)
+	return s
+}
+
+// 请求使用的超时
+func (m *RtuMaster) requestTimeout(addr byte, fun global.FunCode) time.Duration {
+	m.cl.RLock()
+	defer m.cl.RUnlock()
+	if l, ok := m.latency[LatencyKey{Slave: addr, Fun: fun}]; ok {
+		return l.stats.Timeout
+	}
+	return m.timeoutFor(nil)
+}
+
+// 按延时样本计算超时，调用方需要持有配置锁
+func (m *RtuMaster) timeoutFor(l *latency) time.Duration {
+	c := m.timeoutConf
+	ceiling := c.Ceiling
+	if ceiling <= 0 || ceiling > m.readTimeout {
+		ceiling = m.readTimeout
+	}
+	if c.Percentile <= 0 || l == nil || len(l.samples) == 0 || len(l.samples) < c.MinSamples {
+		return ceiling
+	}
+
+	sorted := append([]time.Duration(nil), l.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	i := int(math.Ceil(c.Percentile*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	} else if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+
+	t := sorted[i] + c.Margin
+	if t < c.Floor {
+		t = c.Floor
+	}
+	if t > ceiling {
+		t = ceiling
+	}
+	return t
+}
+
+// 记录一次请求的延时
+// 超时的请求以当时的超时作为样本，使超时逐步放宽，适应变慢的从站
+func (m *RtuMaster) recordLatency(addr byte, fun global.FunCode, d time.Duration, timeout bool) {
+	key := LatencyKey{Slave: addr, Fun: fun}
+	m.cl.Lock()
+	defer m.cl.Unlock()
+	l, ok := m.latency[key]
+	if !ok {
+		l = &latency{stats: LatencyStats{Buckets: make([]LatencyBucket, len(latencyBuckets)+1)}}
+		for i, le := range latencyBuckets {
+			l.stats.Buckets[i].Le = le
+		}
+		m.latency[key] = l
+	}
+
+	if !timeout {
+		s := &l.stats
+		if s.Count == 0 || d < s.Min {
+			s.Min = d
+		}
+		if d > s.Max {
+			s.Max = d
+		}
+		s.Count++
+		i := sort.Search(len(latencyBuckets), func(i int) bool { return d <= latencyBuckets[i] })
+		s.Buckets[i].Count++
+	}
+
+	window := m.timeoutConf.Window
+	if len(l.samples) < window {
+		l.samples = append(l.samples, d)
+	} else {
+		l.samples[l.next] = d
+		l.next = (l.next + 1) % window
+	}
+	l.stats.Timeout = m.timeoutFor(l)
+}
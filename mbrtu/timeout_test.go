@@ -0,0 +1,42 @@
+package mbrtu
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeoutFor(t *testing.T) {
+	ms := time.Millisecond
+	l := &latency{samples: []time.Duration{70 * ms, 10 * ms, 100 * ms, 40 * ms, 20 * ms, 90 * ms, 30 * ms, 60 * ms, 50 * ms, 80 * ms}}
+	tests := []struct {
+		name string
+		c    TimeoutConfig
+		want time.Duration
+	}{
+		{"disabled", TimeoutConfig{}, time.Second},
+		{"p95", TimeoutConfig{Percentile: 0.95}, 100 * ms},
+		{"p90", TimeoutConfig{Percentile: 0.9}, 90 * ms},
+		{"p50", TimeoutConfig{Percentile: 0.5}, 50 * ms},
+		{"p1", TimeoutConfig{Percentile: 0.01}, 10 * ms},
+		{"p100", TimeoutConfig{Percentile: 1}, 100 * ms},
+		{"margin", TimeoutConfig{Percentile: 0.5, Margin: 25 * ms}, 75 * ms},
+		{"floor", TimeoutConfig{Percentile: 0.5, Floor: 200 * ms}, 200 * ms},
+		{"ceiling", TimeoutConfig{Percentile: 0.95, Margin: 50 * ms, Ceiling: 120 * ms}, 120 * ms},
+		{"ceiling above read timeout", TimeoutConfig{Percentile: 0.95, Margin: time.Second, Ceiling: 5 * time.Second}, time.Second},
+		{"too few samples", TimeoutConfig{Percentile: 0.95, MinSamples: 20}, time.Second},
+	}
+	for _, tt := range tests {
+		m := &RtuMaster{readTimeout: time.Second, timeoutConf: tt.c}
+		if got := m.timeoutFor(l); got != tt.want {
+			t.Errorf("%s: timeoutFor = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	m := &RtuMaster{readTimeout: time.Second, timeoutConf: TimeoutConfig{Percentile: 0.95}}
+	if got := m.timeoutFor(nil); got != time.Second {
+		t.Errorf("timeoutFor(nil) = %s, want %s", got, time.Second)
+	}
+	if got := m.timeoutFor(&latency{samples: []time.Duration{30 * ms}}); got != 30*ms {
+		t.Errorf("timeoutFor with one sample = %s, want 30ms", got)
+	}
+}
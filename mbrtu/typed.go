@@ -0,0 +1,207 @@
+package mbrtu
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"ckklearn.com/testmodbus/global"
+	"ckklearn.com/testmodbus/mbrtu/mbcodec"
+)
+
+// 读取指定个数的保持寄存器，返回寄存器数据
+// 寄存器个数超过单次读取的最大个数时返回错误
+func (m *RtuMaster) readRegs(addr byte, offset uint16, regNum int, crcOrder binary.ByteOrder) ([]byte, error) {
+	if regNum > 0xffff {
+		return nil, fmt.Errorf("read num %d out of range", regNum)
+	}
+	return m.ReadAreaBytes(addr, global.AreaHoldingRegister, offset, uint16(regNum), crcOrder)
+}
+
+// ---- 读取 ----
+
+// ReadCoilBits 读取线圈并解包为布尔值
+// 返回`num`个布尔值，从站返回的字节数不符时返回错误
+func (m *RtuMaster) ReadCoilBits(addr byte, offset, num uint16, crcOrder binary.ByteOrder) ([]bool, error) {
+	p, err := m.ReadAreaBytes(addr, global.AreaCoil, offset, num, crcOrder)
+	if err != nil {
+		return nil, err
+	}
+	return mbcodec.Bools(p, int(num))
+}
+
+// ReadInputBits 读取离散输入并解包为布尔值
+// 返回`num`个布尔值，从站返回的字节数不符时返回错误
+func (m *RtuMaster) ReadInputBits(addr byte, offset, num uint16, crcOrder binary.ByteOrder) ([]bool, error) {
+	p, err := m.ReadAreaBytes(addr, global.AreaDiscreteInput, offset, num, crcOrder)
+	if err != nil {
+		return nil, err
+	}
+	return mbcodec.Bools(p, int(num))
+}
+
+// ReadUint16s 读取保持寄存器并解码为uint16
+// `num` 为数值的个数
+func (m *RtuMaster) ReadUint16s(addr byte, offset, num uint16, order mbcodec.Order, crcOrder binary.ByteOrder) ([]uint16, error) {
+	b, err := m.readRegs(addr, offset, int(num), crcOrder)
+	if err != nil {
+		return nil, err
+	}
+	return mbcodec.Uint16s(b, order)
+}
+
+// ReadInt16s 读取保持寄存器并解码为int16
+// `num` 为数值的个数
+func (m *RtuMaster) ReadInt16s(addr byte, offset, num uint16, order mbcodec.Order, crcOrder binary.ByteOrder) ([]int16, error) {
+	b, err := m.readRegs(addr, offset, int(num), crcOrder)
+	if err != nil {
+		return nil, err
+	}
+	return mbcodec.Int16s(b, order)
+}
+
+// ReadUint32s 读取保持寄存器并解码为uint32
+// `num` 为数值的个数，每个数值占2个寄存器
+func (m *RtuMaster) ReadUint32s(addr byte, offset, num uint16, order mbcodec.Order, crcOrder binary.ByteOrder) ([]uint32, error) {
+	b, err := m.readRegs(addr, offset, int(num)*2, crcOrder)
+	if err != nil {
+		return nil, err
+	}
+	return mbcodec.Uint32s(b, order)
+}
+
+// ReadInt32s 读取保持寄存器并解码为int32
+// `num` 为数值的个数，每个数值占2个寄存器
+func (m *RtuMaster) ReadInt32s(addr byte, offset, num uint16, order mbcodec.Order, crcOrder binary.ByteOrder) ([]int32, error) {
+	b, err := m.readRegs(addr, offset, int(num)*2, crcOrder)
+	if err != nil {
+		return nil, err
+	}
+	return mbcodec.Int32s(b, order)
+}
+
+// ReadFloat32s 读取保持寄存器并解码为float32
+// `num` 为数值的个数，每个数值占2个寄存器
+func (m *RtuMaster) ReadFloat32s(addr byte, offset, num uint16, order mbcodec.Order, crcOrder binary.ByteOrder) ([]float32, error) {
+	b, err := m.readRegs(addr, offset, int(num)*2, crcOrder)
+	if err != nil {
+		return nil, err
+	}
+	return mbcodec.Float32s(b, order)
+}
+
+// ReadUint64s 读取保持寄存器并解码为uint64
+// `num` 为数值的个数，每个数值占4个寄存器
+func (m *RtuMaster) ReadUint64s(addr byte, offset, num uint16, order mbcodec.Order, crcOrder binary.ByteOrder) ([]uint64, error) {
+	b, err := m.readRegs(addr, offset, int(num)*4, crcOrder)
+	if err != nil {
+		return nil, err
+	}
+	return mbcodec.Uint64s(b, order)
+}
+
+// ReadInt64s 读取保持寄存器并解码为int64
+// `num` 为数值的个数，每个数值占4个寄存器
+func (m *RtuMaster) ReadInt64s(addr byte, offset, num uint16, order mbcodec.Order, crcOrder binary.ByteOrder) ([]int64, error) {
+	b, err := m.readRegs(addr, offset, int(num)*4, crcOrder)
+	if err != nil {
+		return nil, err
+	}
+	return mbcodec.Int64s(b, order)
+}
+
+// ReadFloat64s 读取保持寄存器并解码为float64
+// `num` 为数值的个数，每个数值占4个寄存器
+func (m *RtuMaster) ReadFloat64s(addr byte, offset, num uint16, order mbcodec.Order, crcOrder binary.ByteOrder) ([]float64, error) {
+	b, err := m.readRegs(addr, offset, int(num)*4, crcOrder)
+	if err != nil {
+		return nil, err
+	}
+	return mbcodec.Float64s(b, order)
+}
+
+// ---- 写入 ----
+
+// WriteUint16s 将uint16编码后写入保持寄存器
+func (m *RtuMaster) WriteUint16s(addr byte, offset uint16, data []uint16, order mbcodec.Order, crcOrder binary.ByteOrder) error {
+	return m.WriteMultiRegisters(addr, offset, mbcodec.EncodeUint16s(data, order), crcOrder)
+}
+
+// WriteInt16s 将int16编码后写入保持寄存器
+func (m *RtuMaster) WriteInt16s(addr byte, offset uint16, data []int16, order mbcodec.Order, crcOrder binary.ByteOrder) error {
+	return m.WriteMultiRegisters(addr, offset, mbcodec.EncodeInt16s(data, order), crcOrder)
+}
+
+// WriteUint32s 将uint32编码后写入保持寄存器
+func (m *RtuMaster) WriteUint32s(addr byte, offset uint16, data []uint32, order mbcodec.Order, crcOrder binary.ByteOrder) error {
+	return m.WriteMultiRegisters(addr, offset, mbcodec.EncodeUint32s(data, order), crcOrder)
+}
+
+// WriteInt32s 将int32编码后写入保持寄存器
+func (m *RtuMaster) WriteInt32s(addr byte, offset uint16, data []int32, order mbcodec.Order, crcOrder binary.ByteOrder) error {
+	return m.WriteMultiRegisters(addr, offset, mbcodec.EncodeInt32s(data, order), crcOrder)
+}
+
+// WriteFloat32s 将float32编码后写入保持寄存器
+func (m *RtuMaster) WriteFloat32s(addr byte, offset uint16, data []float32, order mbcodec.Order, crcOrder binary.ByteOrder) error {
+	return m.WriteMultiRegisters(addr, offset, mbcodec.EncodeFloat32s(data, order), crcOrder)
+}
+
+// WriteUint64s 将uint64编码后写入保持寄存器
+func (m *RtuMaster) WriteUint64s(addr byte, offset uint16, data []uint64, order mbcodec.Order, crcOrder binary.ByteOrder) error {
+	return m.WriteMultiRegisters(addr, offset, mbcodec.EncodeUint64s(data, order), crcOrder)
+}
+
+// WriteInt64s 将int64编码后写入保持寄存器
+func (m *RtuMaster) WriteInt64s(addr byte, offset uint16, data []int64, order mbcodec.Order, crcOrder binary.ByteOrder) error {
+	return m.WriteMultiRegisters(addr, offset, mbcodec.EncodeInt64s(data, order), crcOrder)
+}
+
+// WriteFloat64s 将float64编码后写入保持寄存器
+func (m *RtuMaster) WriteFloat64s(addr byte, offset uint16, data []float64, order mbcodec.Order, crcOrder binary.ByteOrder) error {
+	return m.WriteMultiRegisters(addr, offset, mbcodec.EncodeFloat64s(data, order), crcOrder)
+}
+
+// ---- 字符串 ----
+
+// ReadString 读取保持寄存器并解码为字符串
+// `regNum` 为字符串占用的寄存器个数
+func (m *RtuMaster) ReadString(addr byte, offset, regNum uint16, opt mbcodec.StringOptions, crcOrder binary.ByteOrder) (string, error) {
+	b, err := m.readRegs(addr, offset, int(regNum), crcOrder)
+	if err != nil {
+		return "", err
+	}
+	return mbcodec.String(b, opt)
+}
+
+// WriteString 将字符串编码后写入保持寄存器
+// 字符串补齐到`regNum`个寄存器，超长时返回错误
+func (m *RtuMaster) WriteString(addr byte, offset, regNum uint16, s string, opt mbcodec.StringOptions, crcOrder binary.ByteOrder) error {
+	regs, err := mbcodec.EncodeString(s, int(regNum), opt)
+	if err != nil {
+		return err
+	}
+	return m.WriteMultiRegisters(addr, offset, regs, crcOrder)
+}
+
+// ---- 时间 ----
+
+// ReadTime 读取保持寄存器并解码为时间
+// 不含时区的格式按`loc`解析，`loc` 为nil时使用本地时区
+func (m *RtuMaster) ReadTime(addr byte, offset uint16, layout mbcodec.TimeLayout, order mbcodec.Order, loc *time.Location, crcOrder binary.ByteOrder) (time.Time, error) {
+	b, err := m.readRegs(addr, offset, layout.Registers(), crcOrder)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return mbcodec.DecodeTime(b, layout, order, loc)
+}
+
+// WriteTime 将时间编码后写入保持寄存器
+// 用于设置从站时钟，不含时区的格式按`loc`转换，`loc` 为nil时使用本地时区
+func (m *RtuMaster) WriteTime(addr byte, offset uint16, t time.Time, layout mbcodec.TimeLayout, order mbcodec.Order, loc *time.Location, crcOrder binary.ByteOrder) error {
+	regs, err := mbcodec.EncodeTime(t, layout, order, loc)
+	if err != nil {
+		return err
+	}
+	return m.WriteMultiRegisters(addr, offset, regs, crcOrder)
+}
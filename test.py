@@ -2,13 +2,14 @@
 
 import logging
 from typing import List
-from ctypes import CDLL, c_int, c_char, c_char_p, c_ubyte, c_uint, \
-    c_ushort, string_at
+from ctypes import CDLL, c_int, c_char, c_char_p, c_double, c_ubyte, \
+    c_uint, c_ushort, string_at
 
 from IPython import embed
 
 BIG_ENDIAN = b'>'
 LITTLE_ENDIAN = b'<'
+AUTO_CRC_ORDER = b'a'
 
 ErrString = c_ubyte * 1024
 ReadData = c_ubyte * 1024
@@ -31,6 +32,11 @@ class ModbusDll:
         self._mb_open.argtypes = (c_char_p, c_uint, c_ubyte, c_char, c_ubyte,
                                   c_uint, ErrString)
 
+        self._mb_open_ms = self._dll.OpenMs
+        self._mb_open_ms.restype = c_int
+        self._mb_open_ms.argtypes = (c_char_p, c_uint, c_ubyte, c_char,
+                                     c_ubyte, c_uint, ErrString)
+
         self._mb_close = self._dll.Close
         self._mb_close.restype = c_int
         self._mb_close.argtypes = (ErrString, )
@@ -57,6 +63,11 @@ class ModbusDll:
                                                       c_ubyte, c_ushort,
                                                       c_char, ErrString)
 
+        self._mb_set_adaptive_timeout = self._dll.SetAdaptiveTimeout
+        self._mb_set_adaptive_timeout.restype = c_int
+        self._mb_set_adaptive_timeout.argtypes = (c_double, c_uint, c_uint,
+                                                  c_uint, c_uint, ErrString)
+
     def _last_err_msg(self) -> str:
         return string_at(self._err_string).decode('utf-8')
 
@@ -71,6 +82,15 @@ class ModbusDll:
             return False
         return True
 
+    def open_ms(self, name: str, timeout_ms: int) -> bool:
+        # `open` 的超时单位为秒，这里为毫秒，linux下精度为100毫秒
+        res = self._mb_open_ms(name.encode('utf-8'), 9600, 8, b'n', 1,
+                               timeout_ms, self._err_string)
+        if res < 0:
+            logging.error(self._last_err_msg())
+            return False
+        return True
+
     def close(self) -> bool:
         res = self._mb_close(self._err_string)
         if res < 0:
@@ -116,6 +136,17 @@ class ModbusDll:
             return False
         return True
 
+    def set_adaptive_timeout(self, percentile: float, margin_ms: int,
+                             floor_ms: int, ceiling_ms: int,
+                             min_samples: int) -> bool:
+        res = self._mb_set_adaptive_timeout(percentile, margin_ms, floor_ms,
+                                            ceiling_ms, min_samples,
+                                            self._err_string)
+        if res < 0:
+            logging.error(self._last_err_msg())
+            return False
+        return True
+
 
 if __name__ == '__main__':
     logging.basicConfig(